@@ -17,13 +17,18 @@ limitations under the License.
 package main
 
 import (
+	// Embed the time zone database for the time zones of the schedule windows.
+	_ "time/tzdata"
+
 	// The set of controllers this controller process runs.
 	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa"
 
 	// This defines the shared main for injected controllers.
+	"knative.dev/pkg/injection"
 	"knative.dev/pkg/injection/sharedmain"
 )
 
 func main() {
+	hpa.RegisterEventingInformers(injection.Default)
 	sharedmain.Main("hpaautoscaler", hpa.NewController)
 }
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	// Embed the time zone database for the time zones of the schedule windows.
+	_ "time/tzdata"
+
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection/sharedmain"
+	"knative.dev/pkg/signals"
+	"knative.dev/pkg/webhook"
+	"knative.dev/pkg/webhook/certificates"
+	"knative.dev/pkg/webhook/resourcesemantics/validation"
+
+	kedawebhook "knative.dev/autoscaler-keda/pkg/webhook"
+)
+
+func newValidationAdmissionController(ctx context.Context, _ configmap.Watcher) *controller.Impl {
+	return validation.NewAdmissionController(ctx,
+		// Name of the resource webhook.
+		"validation.autoscaler-keda.knative.dev",
+
+		// The path on which to serve the webhook.
+		"/resource-validation",
+
+		// The resources to validate.
+		kedawebhook.Types,
+
+		// A function that infuses the context passed to Validate.
+		func(ctx context.Context) context.Context {
+			return ctx
+		},
+
+		// Whether to disallow unknown fields, which are validated by Knative Serving.
+		false,
+	)
+}
+
+func main() {
+	ctx := webhook.WithOptions(signals.NewContext(), webhook.Options{
+		ServiceName: "autoscaler-keda-webhook",
+		Port:        webhook.PortFromEnv(8443),
+		SecretName:  "autoscaler-keda-webhook-certs",
+	})
+
+	sharedmain.MainWithContext(ctx, "autoscaler-keda-webhook",
+		certificates.NewController,
+		newValidationAdmissionController,
+	)
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
 	cm "knative.dev/pkg/configmap"
@@ -29,6 +33,13 @@ const (
 	// configuration related to Autoscaler-Keda.
 	AutoscalerKedaConfigName = "config-autoscaler-keda"
 	DefaultPrometheusAddress = "http://prometheus-operated.default.svc:9090"
+	// DefaultHPACreationDeadline is how long to wait for KEDA to create the HPA
+	// of a ScaledObject before the PodAutoscaler is marked inactive.
+	DefaultHPACreationDeadline = 10 * time.Minute
+
+	// TriggerTemplateKeyPrefix is the prefix of the keys defining a named trigger
+	// template, followed by the name of the template.
+	TriggerTemplateKeyPrefix = "autoscaler.keda.trigger-template."
 )
 
 // AutoscalerKedaConfig contains autoscaler keda related configuration defined in the
@@ -36,6 +47,26 @@ const (
 type AutoscalerKedaConfig struct {
 	PrometheusAddress        string
 	ShouldCreateScaledObject bool
+	HPACreationDeadline      time.Duration
+	// PrometheusAuthName, PrometheusAuthKind and PrometheusAuthModes reference the
+	// TriggerAuthentication of the revisions not specifying their own.
+	PrometheusAuthName  string
+	PrometheusAuthKind  string
+	PrometheusAuthModes string
+	// PollingInterval, CooldownPeriod and InitialCooldownPeriod are the defaults of the
+	// ScaledObject fields in seconds, KEDA's defaults are used if they are nil.
+	PollingInterval       *int32
+	CooldownPeriod        *int32
+	InitialCooldownPeriod *int32
+	// Fallback is the number of replicas to scale to once fetching the metrics of the
+	// triggers failed FailureThreshold times in a row, or nil to disable the fallback.
+	Fallback *v1alpha1.Fallback
+	// KafkaBootstrapServers are the Kafka brokers of the consumer lag trigger of the
+	// revisions not specifying their own.
+	KafkaBootstrapServers string
+	// TriggerTemplates are the triggers revisions can reference by name. Their
+	// metadata values are templates rendered for each revision.
+	TriggerTemplates map[string]v1alpha1.ScaleTriggers
 }
 
 // NewAutoscalerKedaConfigFromConfigMap creates an AutoscalerKedaConfig from the supplied ConfigMap
@@ -43,20 +74,115 @@ func NewConfigFromMap(data map[string]string) (*AutoscalerKedaConfig, error) {
 	config := &AutoscalerKedaConfig{
 		PrometheusAddress:        DefaultPrometheusAddress,
 		ShouldCreateScaledObject: true,
+		HPACreationDeadline:      DefaultHPACreationDeadline,
 	}
+	var fallback v1alpha1.Fallback
 	if err := cm.Parse(data,
 		cm.AsString("autoscaler.keda.prometheus-address", &config.PrometheusAddress),
 		cm.AsBool("autoscaler.keda.scaledobject-autocreate", &config.ShouldCreateScaledObject),
+		cm.AsDuration("autoscaler.keda.hpa-creation-deadline", &config.HPACreationDeadline),
+		cm.AsString("autoscaler.keda.prometheus-auth-name", &config.PrometheusAuthName),
+		cm.AsString("autoscaler.keda.prometheus-auth-kind", &config.PrometheusAuthKind),
+		cm.AsString("autoscaler.keda.prometheus-auth-modes", &config.PrometheusAuthModes),
+		cm.AsString("autoscaler.keda.kafka-bootstrap-servers", &config.KafkaBootstrapServers),
+		asOptionalSeconds("autoscaler.keda.polling-interval", &config.PollingInterval),
+		asOptionalSeconds("autoscaler.keda.cooldown-period", &config.CooldownPeriod),
+		asOptionalSeconds("autoscaler.keda.initial-cooldown-period", &config.InitialCooldownPeriod),
+		cm.AsInt32("autoscaler.keda.fallback-failure-threshold", &fallback.FailureThreshold),
+		cm.AsInt32("autoscaler.keda.fallback-replicas", &fallback.Replicas),
 	); err != nil {
 		return nil, fmt.Errorf("failed to parse data: %w", err)
 	}
 
+	if config.PollingInterval != nil && *config.PollingInterval == 0 {
+		return nil, fmt.Errorf("autoscaler.keda.polling-interval must be at least 1s")
+	}
+
+	if fallback.FailureThreshold < 0 || fallback.Replicas < 0 {
+		return nil, fmt.Errorf("autoscaler.keda.fallback-failure-threshold and autoscaler.keda.fallback-replicas must not be negative, were: %d and %d",
+			fallback.FailureThreshold, fallback.Replicas)
+	}
+	if fallback.FailureThreshold > 0 {
+		config.Fallback = &fallback
+	} else if fallback.Replicas > 0 {
+		return nil, fmt.Errorf("autoscaler.keda.fallback-failure-threshold must be specified with autoscaler.keda.fallback-replicas")
+	}
+
+	if config.HPACreationDeadline <= 0 {
+		return nil, fmt.Errorf("autoscaler.keda.hpa-creation-deadline must be positive, was: %v", config.HPACreationDeadline)
+	}
+
 	if err := helpers.ParseServerAddress(config.PrometheusAddress); err != nil {
 		return nil, err
 	}
+
+	if config.PrometheusAuthName == "" {
+		if config.PrometheusAuthKind != "" || config.PrometheusAuthModes != "" {
+			return nil, fmt.Errorf("autoscaler.keda.prometheus-auth-name must be specified with the auth kind and modes")
+		}
+	} else {
+		if config.PrometheusAuthKind != "TriggerAuthentication" && config.PrometheusAuthKind != "ClusterTriggerAuthentication" {
+			return nil, fmt.Errorf("autoscaler.keda.prometheus-auth-kind must be TriggerAuthentication or ClusterTriggerAuthentication, was: %q", config.PrometheusAuthKind)
+		}
+		if config.PrometheusAuthModes == "" {
+			return nil, fmt.Errorf("autoscaler.keda.prometheus-auth-modes must be specified with the auth name")
+		}
+	}
+
+	if err := parseTriggerTemplates(data, config); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// asOptionalSeconds parses the value at key as a duration of whole seconds into the target,
+// if it exists.
+func asOptionalSeconds(key string, target **int32) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %w", key, err)
+		}
+		if d < 0 || d%time.Second != 0 {
+			return fmt.Errorf("%s must be a positive whole number of seconds, was: %v", key, d)
+		}
+		seconds := int32(d.Seconds())
+		*target = &seconds
+		return nil
+	}
+}
+
+// parseTriggerTemplates reads the trigger templates defined as JSON ScaleTriggers under
+// the TriggerTemplateKeyPrefix keys. The name of a template is the name of its trigger.
+func parseTriggerTemplates(data map[string]string, config *AutoscalerKedaConfig) error {
+	for k, v := range data {
+		name, ok := strings.CutPrefix(k, TriggerTemplateKeyPrefix)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("%s must be followed by the name of the template", TriggerTemplateKeyPrefix)
+		}
+		var trigger v1alpha1.ScaleTriggers
+		if err := json.Unmarshal([]byte(v), &trigger); err != nil {
+			return fmt.Errorf("unable to unmarshal trigger template %q: %w", name, err)
+		}
+		if trigger.Type == "" {
+			return fmt.Errorf("trigger template %q has no type", name)
+		}
+		trigger.Name = name
+		if config.TriggerTemplates == nil {
+			config.TriggerTemplates = make(map[string]v1alpha1.ScaleTriggers)
+		}
+		config.TriggerTemplates[name] = trigger
+	}
+	return nil
+}
+
 // NewAutoscalerKedaConfigFromConfigMap creates an AutoscalerKedaConfig from the supplied ConfigMap
 func NewAutoscalerKedaConfigFromConfigMap(configMap *corev1.ConfigMap) (*AutoscalerKedaConfig, error) {
 	return NewConfigFromMap(configMap.Data)
@@ -18,6 +18,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	configmaptesting "knative.dev/pkg/configmap/testing"
 )
@@ -33,3 +34,123 @@ func TestAutoscalerKedaConfig(t *testing.T) {
 		t.Errorf("NewAutoscalerKedaConfigFromConfigMap(actual) = %v", err)
 	}
 }
+
+func TestAutoscalerKedaConfigHPACreationDeadline(t *testing.T) {
+	if _, err := NewConfigFromMap(map[string]string{
+		"autoscaler.keda.hpa-creation-deadline": "-1m",
+	}); err == nil {
+		t.Error("NewConfigFromMap() = nil, wanted error for a negative deadline")
+	}
+
+	config, err := NewConfigFromMap(map[string]string{
+		"autoscaler.keda.hpa-creation-deadline": "90s",
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	if got, want := config.HPACreationDeadline, 90*time.Second; got != want {
+		t.Errorf("HPACreationDeadline = %v, want: %v", got, want)
+	}
+}
+
+func TestAutoscalerKedaConfigTriggerTemplates(t *testing.T) {
+	config, err := NewConfigFromMap(map[string]string{
+		TriggerTemplateKeyPrefix + "http-rate": `{"type": "prometheus", "metadata": {"query": "sum(rate({{ .params.metric }}[1m]))"}}`,
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	trigger, ok := config.TriggerTemplates["http-rate"]
+	if !ok {
+		t.Fatalf("TriggerTemplates = %v, want the http-rate template", config.TriggerTemplates)
+	}
+	if got, want := trigger.Name, "http-rate"; got != want {
+		t.Errorf("Name = %s, want: %s", got, want)
+	}
+
+	for name, data := range map[string]map[string]string{
+		"invalid json": {TriggerTemplateKeyPrefix + "http-rate": `{"type": `},
+		"missing type": {TriggerTemplateKeyPrefix + "http-rate": `{"metadata": {}}`},
+		"missing name": {TriggerTemplateKeyPrefix: `{"type": "prometheus"}`},
+	} {
+		if _, err := NewConfigFromMap(data); err == nil {
+			t.Errorf("NewConfigFromMap() = nil, wanted error for %s", name)
+		}
+	}
+}
+
+func TestAutoscalerKedaConfigPrometheusAuth(t *testing.T) {
+	config, err := NewConfigFromMap(map[string]string{
+		"autoscaler.keda.prometheus-auth-name":  "keda-trigger-auth-prometheus",
+		"autoscaler.keda.prometheus-auth-kind":  "ClusterTriggerAuthentication",
+		"autoscaler.keda.prometheus-auth-modes": "bearer",
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	if got, want := config.PrometheusAuthKind, "ClusterTriggerAuthentication"; got != want {
+		t.Errorf("PrometheusAuthKind = %s, want: %s", got, want)
+	}
+
+	for name, data := range map[string]map[string]string{
+		"invalid kind": {
+			"autoscaler.keda.prometheus-auth-name":  "keda-trigger-auth-prometheus",
+			"autoscaler.keda.prometheus-auth-kind":  "Secret",
+			"autoscaler.keda.prometheus-auth-modes": "bearer",
+		},
+		"missing kind": {
+			"autoscaler.keda.prometheus-auth-name":  "keda-trigger-auth-prometheus",
+			"autoscaler.keda.prometheus-auth-modes": "bearer",
+		},
+		"missing modes": {
+			"autoscaler.keda.prometheus-auth-name": "keda-trigger-auth-prometheus",
+			"autoscaler.keda.prometheus-auth-kind": "TriggerAuthentication",
+		},
+		"missing name": {
+			"autoscaler.keda.prometheus-auth-kind":  "TriggerAuthentication",
+			"autoscaler.keda.prometheus-auth-modes": "bearer",
+		},
+	} {
+		if _, err := NewConfigFromMap(data); err == nil {
+			t.Errorf("NewConfigFromMap() = nil, wanted error for %s", name)
+		}
+	}
+}
+
+func TestAutoscalerKedaConfigScaledObjectDefaults(t *testing.T) {
+	config, err := NewConfigFromMap(map[string]string{
+		"autoscaler.keda.polling-interval":           "15s",
+		"autoscaler.keda.cooldown-period":            "0s",
+		"autoscaler.keda.initial-cooldown-period":    "2m",
+		"autoscaler.keda.fallback-failure-threshold": "3",
+		"autoscaler.keda.fallback-replicas":          "2",
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	if config.PollingInterval == nil || *config.PollingInterval != 15 {
+		t.Errorf("PollingInterval = %v, want: 15", config.PollingInterval)
+	}
+	if config.CooldownPeriod == nil || *config.CooldownPeriod != 0 {
+		t.Errorf("CooldownPeriod = %v, want: 0", config.CooldownPeriod)
+	}
+	if config.InitialCooldownPeriod == nil || *config.InitialCooldownPeriod != 120 {
+		t.Errorf("InitialCooldownPeriod = %v, want: 120", config.InitialCooldownPeriod)
+	}
+	if config.Fallback == nil || config.Fallback.FailureThreshold != 3 || config.Fallback.Replicas != 2 {
+		t.Errorf("Fallback = %v, want: 3 failures and 2 replicas", config.Fallback)
+	}
+
+	for name, data := range map[string]map[string]string{
+		"zero polling interval":    {"autoscaler.keda.polling-interval": "0s"},
+		"fractional cooldown":      {"autoscaler.keda.cooldown-period": "1.5s"},
+		"negative cooldown":        {"autoscaler.keda.initial-cooldown-period": "-1m"},
+		"negative fallback":        {"autoscaler.keda.fallback-failure-threshold": "-3"},
+		"fallback replicas only":   {"autoscaler.keda.fallback-replicas": "2"},
+		"invalid polling interval": {"autoscaler.keda.polling-interval": "often"},
+	} {
+		if _, err := NewConfigFromMap(data); err == nil {
+			t.Errorf("NewConfigFromMap() = nil, wanted error for %s", name)
+		}
+	}
+}
@@ -21,9 +21,40 @@ limitations under the License.
 
 package config
 
+import (
+	v1alpha1 "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+)
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalerKedaConfig) DeepCopyInto(out *AutoscalerKedaConfig) {
 	*out = *in
+	if in.PollingInterval != nil {
+		in, out := &in.PollingInterval, &out.PollingInterval
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CooldownPeriod != nil {
+		in, out := &in.CooldownPeriod, &out.CooldownPeriod
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InitialCooldownPeriod != nil {
+		in, out := &in.InitialCooldownPeriod, &out.InitialCooldownPeriod
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Fallback != nil {
+		in, out := &in.Fallback, &out.Fallback
+		*out = new(v1alpha1.Fallback)
+		**out = **in
+	}
+	if in.TriggerTemplates != nil {
+		in, out := &in.TriggerTemplates, &out.TriggerTemplates
+		*out = make(map[string]v1alpha1.ScaleTriggers, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	return
 }
 
@@ -19,19 +19,26 @@ package hpa
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
 
 	networkingclient "knative.dev/networking/pkg/client/injection/client"
 	sksinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/serverlessservice"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
 	hpainformer "knative.dev/pkg/client/injection/kube/informers/autoscaling/v2/horizontalpodautoscaler"
+	namespaceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/serving/pkg/apis/autoscaling"
 	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 	servingclient "knative.dev/serving/pkg/client/injection/client"
 	metricinformer "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/metric"
@@ -43,6 +50,7 @@ import (
 	kedaclientinjection "knative.dev/autoscaler-keda/pkg/client/injection/client"
 	keda "knative.dev/autoscaler-keda/pkg/client/injection/informers/keda/v1alpha1/scaledobject"
 	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/resources"
 )
 
 // NewController returns a new KEDA ScaledObject reconcile controller.
@@ -56,6 +64,8 @@ func NewController(
 	hpaInformer := hpainformer.Get(ctx)
 	metricInformer := metricinformer.Get(ctx)
 	kedaInformer := keda.Get(ctx)
+	deploymentInformer := deploymentinformer.Get(ctx)
+	namespaceInformer := namespaceinformer.Get(ctx)
 
 	onlyHPAClass := pkgreconciler.AnnotationFilterFunc(autoscaling.ClassAnnotationKey, autoscaling.HPA, false)
 
@@ -71,6 +81,14 @@ func NewController(
 		kedaLister: kedaInformer.Lister(),
 		kedaClient: kedaclientinjection.Get(ctx),
 		hpaLister:  hpaInformer.Lister(),
+
+		deploymentLister: deploymentInformer.Lister(),
+		namespaceLister:  namespaceInformer.Lister(),
+	}
+
+	eventingInformers := getEventingInformers(ctx)
+	for _, informer := range eventingInformers {
+		c.eventingListers = append(c.eventingListers, informer.Lister())
 	}
 	impl := pareconciler.NewImpl(ctx, c, autoscaling.HPA, func(impl *controller.Impl) controller.Options {
 		logger.Info("Setting up ConfigMap receivers")
@@ -104,14 +122,87 @@ func NewController(
 		Kind:  "ScaledObject",
 	}
 	onlyKEDAControlled := controller.FilterControllerGK(gk)
-	handleMatchingControllersForHPA := cache.FilteringResourceEventHandler{
-		FilterFunc: pkgreconciler.ChainFilterFuncs(onlyHPAClass, onlyKEDAControlled),
-		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+
+	// Revision deployments are labeled with the revision name, which is also the name of the PA.
+	enqueueRevision := impl.EnqueueLabelOfNamespaceScopedResource("", serving.RevisionLabelKey)
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: pkgreconciler.LabelExistsFilterFunc(serving.RevisionLabelKey),
+		Handler:    controller.HandleAll(enqueueRevision),
+	})
+
+	// ScaledObjects brought by users are neither owned by nor named after the PA,
+	// so the PA is found through the deployment the ScaledObject scales.
+	enqueueScaleTargetOf := func(so *kedav1alpha1.ScaledObject) {
+		if so.Spec.ScaleTargetRef == nil {
+			return
+		}
+		deployment, err := deploymentInformer.Lister().Deployments(so.Namespace).Get(so.Spec.ScaleTargetRef.Name)
+		if err != nil {
+			return
+		}
+		enqueueRevision(deployment)
 	}
 
-	hpaInformer.Informer().AddEventHandler(handleMatchingControllersForHPA)
+	// The HPA may be named by KEDA, enqueue the PA of the ScaledObject controlling it.
+	hpaInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: onlyKEDAControlled,
+		Handler: controller.HandleAll(func(obj interface{}) {
+			object, err := kmeta.DeletionHandlingAccessor(obj)
+			if err != nil {
+				return
+			}
+			owner := metav1.GetControllerOf(object)
+			so, err := kedaInformer.Lister().ScaledObjects(object.GetNamespace()).Get(owner.Name)
+			if err != nil {
+				return
+			}
+			enqueueScaleTargetOf(so)
+		}),
+	})
 	sksInformer.Informer().AddEventHandler(handleMatchingControllers)
 	metricInformer.Informer().AddEventHandler(handleMatchingControllers)
+	// Recreate or revert the ScaledObjects we manage as soon as they are deleted or modified.
+	kedaInformer.Informer().AddEventHandler(handleMatchingControllers)
+	kedaInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: pkgreconciler.Not(onlyPAControlled),
+		Handler: controller.HandleAll(func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if so, ok := obj.(*kedav1alpha1.ScaledObject); ok {
+				enqueueScaleTargetOf(so)
+			}
+		}),
+	})
+
+	// Namespaces provide defaults to the revisions they contain, only resync the PAs
+	// of a namespace when its defaults change.
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNs, ok := oldObj.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			newNs, ok := newObj.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			if equality.Semantic.DeepEqual(resources.NamespaceDefaults(oldNs), resources.NamespaceDefaults(newNs)) {
+				return
+			}
+			if err := resources.ValidateAnnotations(resources.NamespaceDefaults(newNs)); err != nil {
+				logger.Errorf("Invalid autoscaling defaults on namespace %q: %v", newNs.Name, err)
+			}
+			impl.FilteredGlobalResync(pkgreconciler.ChainFilterFuncs(onlyHPAClass,
+				pkgreconciler.NamespaceFilterFunc(newNs.Name)), paInformer.Informer())
+		},
+	})
+
+	// The triggers of the revisions of a Service are derived from the sources whose sink
+	// is the Service, enqueue its PAs when one changes.
+	for _, informer := range eventingInformers {
+		informer.Informer().AddEventHandler(enqueueSinkPAs(paInformer.Lister(), onlyHPAClass, impl.Enqueue))
+	}
 
 	return impl
 }
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/injection/clients/dynamicclient"
+	"knative.dev/pkg/logging"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	autoscalingv1alpha1listers "knative.dev/serving/pkg/client/listers/autoscaling/v1alpha1"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/resources"
+)
+
+// RegisterEventingInformers registers an informer per eventing source resource, so that the
+// sources are started and synced along with the other informers of the controller. The
+// sources are watched without depending on their types, and only if the cluster serves them
+// when the controller starts. It must be called before the injection sets up the informers,
+// e.g. before sharedmain.Main.
+func RegisterEventingInformers(i injection.Interface) {
+	for _, gvr := range resources.EventingSourceResources {
+		i.RegisterInformer(func(ctx context.Context) (context.Context, controller.Informer) {
+			return withEventingInformer(ctx, gvr)
+		})
+	}
+}
+
+// eventingInformerKey is the context key of the informer of an eventing source resource.
+type eventingInformerKey struct {
+	gvr schema.GroupVersionResource
+}
+
+// withEventingInformer sets up the informer of the eventing source resource, or a no-op
+// informer if the cluster does not serve the resource.
+func withEventingInformer(ctx context.Context, gvr schema.GroupVersionResource) (context.Context, controller.Informer) {
+	if !isServed(ctx, kubeclient.Get(ctx).Discovery(), gvr) {
+		logging.FromContext(ctx).Infof("Not deriving triggers from %s, which the cluster does not serve", gvr)
+		return ctx, noopInformer{}
+	}
+	informer := dynamicinformer.NewFilteredDynamicInformer(dynamicclient.Get(ctx), gvr, metav1.NamespaceAll,
+		controller.GetResyncPeriod(ctx), cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, nil)
+	return context.WithValue(ctx, eventingInformerKey{gvr}, informer), informer.Informer()
+}
+
+// getEventingInformers returns the informers of the eventing sources the cluster serves.
+func getEventingInformers(ctx context.Context) []informers.GenericInformer {
+	var infs []informers.GenericInformer
+	for _, gvr := range resources.EventingSourceResources {
+		if informer, ok := ctx.Value(eventingInformerKey{gvr}).(informers.GenericInformer); ok {
+			infs = append(infs, informer)
+		}
+	}
+	return infs
+}
+
+// isServed returns whether the cluster serves the resource. Discovery errors other than
+// the group version not being found are logged, and the resource is not watched.
+func isServed(ctx context.Context, d discovery.DiscoveryInterface, gvr schema.GroupVersionResource) bool {
+	list, err := d.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		logging.FromContext(ctx).Errorw("Failed to discover the eventing source resource "+gvr.String(), zap.Error(err))
+		return false
+	}
+	for _, r := range list.APIResources {
+		if r.Name == gvr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// noopInformer stands for the informer of a resource the cluster does not serve.
+type noopInformer struct{}
+
+func (noopInformer) Run(<-chan struct{}) {}
+
+func (noopInformer) HasSynced() bool { return true }
+
+// enqueueSinkPAs returns the handler enqueueing the routed PAs of the Service a source
+// delivers its events to, before and after the source changes.
+func enqueueSinkPAs(paLister autoscalingv1alpha1listers.PodAutoscalerLister, filter func(interface{}) bool, enqueue func(interface{})) cache.ResourceEventHandler {
+	enqueueSink := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		source, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		service, ok := resources.EventingSinkService(source)
+		if !ok {
+			return
+		}
+		pas, err := paLister.PodAutoscalers(source.GetNamespace()).List(labels.SelectorFromSet(labels.Set{
+			serving.ServiceLabelKey: service,
+		}))
+		if err != nil {
+			return
+		}
+		for _, pa := range pas {
+			if filter(pa) && pa.Spec.Reachability == autoscalingv1alpha1.ReachabilityReachable {
+				enqueue(pa)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueueSink,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enqueueSink(oldObj)
+			enqueueSink(newObj)
+		},
+		DeleteFunc: enqueueSink,
+	}
+}
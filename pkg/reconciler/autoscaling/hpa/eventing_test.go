@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	autoscalingv1alpha1listers "knative.dev/serving/pkg/client/listers/autoscaling/v1alpha1"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+
+	. "knative.dev/serving/pkg/testing" //nolint:all
+)
+
+func withServiceLabel(service string) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		pa.Labels = map[string]string{serving.ServiceLabelKey: service}
+	}
+}
+
+func TestEnqueueSinkPAs(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pa := range []*autoscalingv1alpha1.PodAutoscaler{
+		helpers.PodAutoscaler(helpers.TestNamespace, "shop-00001", WithHPAClass, WithReachabilityReachable, withServiceLabel("shop")),
+		helpers.PodAutoscaler(helpers.TestNamespace, "shop-00002", WithHPAClass, WithReachabilityReachable, withServiceLabel("shop")),
+		helpers.PodAutoscaler(helpers.TestNamespace, "billing-00001", WithHPAClass, WithReachabilityReachable, withServiceLabel("billing")),
+		helpers.PodAutoscaler(helpers.TestNamespace, "cart-00001", WithHPAClass, WithReachabilityReachable, withServiceLabel("cart")),
+		// The PAs of other classes or namespaces, and the PAs of revisions without routes, are not enqueued.
+		helpers.PodAutoscaler(helpers.TestNamespace, "shop-kpa", WithReachabilityReachable, withServiceLabel("shop")),
+		helpers.PodAutoscaler(helpers.TestNamespace, "shop-00003", WithHPAClass, WithReachabilityUnreachable, withServiceLabel("shop")),
+		helpers.PodAutoscaler("other", "shop-00001", WithHPAClass, WithReachabilityReachable, withServiceLabel("shop")),
+	} {
+		indexer.Add(pa)
+	}
+
+	source := func(service string) *unstructured.Unstructured {
+		s := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"sink": map[string]interface{}{"ref": map[string]interface{}{
+					"apiVersion": "serving.knative.dev/v1",
+					"kind":       "Service",
+					"name":       service,
+				}},
+			},
+		}}
+		s.SetAPIVersion("sources.knative.dev/v1beta1")
+		s.SetKind("KafkaSource")
+		s.SetNamespace(helpers.TestNamespace)
+		s.SetName("orders")
+		return s
+	}
+
+	enqueued := sets.New[string]()
+	handler := enqueueSinkPAs(autoscalingv1alpha1listers.NewPodAutoscalerLister(indexer),
+		pkgreconciler.AnnotationFilterFunc(autoscaling.ClassAnnotationKey, autoscaling.HPA, false),
+		func(obj interface{}) {
+			pa := obj.(*autoscalingv1alpha1.PodAutoscaler)
+			enqueued.Insert(pa.Namespace + "/" + pa.Name)
+		})
+
+	handler.OnAdd(source("shop"), false)
+	want := sets.New(helpers.TestNamespace+"/shop-00001", helpers.TestNamespace+"/shop-00002")
+	if diff := cmp.Diff(sets.List(want), sets.List(enqueued)); diff != "" {
+		t.Errorf("Enqueued PAs on add mismatch: diff(-want,+got):\n%s", diff)
+	}
+
+	// The PAs of both the old and the new sink are updated.
+	enqueued.Clear()
+	handler.OnUpdate(source("shop"), source("billing"))
+	want = sets.New(helpers.TestNamespace+"/shop-00001", helpers.TestNamespace+"/shop-00002", helpers.TestNamespace+"/billing-00001")
+	if diff := cmp.Diff(sets.List(want), sets.List(enqueued)); diff != "" {
+		t.Errorf("Enqueued PAs on update mismatch: diff(-want,+got):\n%s", diff)
+	}
+
+	enqueued.Clear()
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Obj: source("billing")})
+	want = sets.New(helpers.TestNamespace + "/billing-00001")
+	if diff := cmp.Diff(sets.List(want), sets.List(enqueued)); diff != "" {
+		t.Errorf("Enqueued PAs on delete mismatch: diff(-want,+got):\n%s", diff)
+	}
+}
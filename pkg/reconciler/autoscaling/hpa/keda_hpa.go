@@ -18,20 +18,31 @@ package hpa
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"math"
 	"strconv"
+	"time"
 
 	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"knative.dev/pkg/apis"
 	"knative.dev/serving/pkg/apis/autoscaling"
 
 	v2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	autoscalingv2listers "k8s.io/client-go/listers/autoscaling/v2"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 
 	nv1alpha1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	pkgreconciler "knative.dev/pkg/reconciler"
@@ -39,6 +50,7 @@ import (
 	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 	pareconciler "knative.dev/serving/pkg/client/injection/reconciler/autoscaling/v1alpha1/podautoscaler"
 	areconciler "knative.dev/serving/pkg/reconciler/autoscaling"
+	aresources "knative.dev/serving/pkg/reconciler/autoscaling/resources"
 
 	"github.com/kedacore/keda/v2/pkg/generated/clientset/versioned"
 	kedav1alpha1 "github.com/kedacore/keda/v2/pkg/generated/listers/keda/v1alpha1"
@@ -47,8 +59,33 @@ import (
 )
 
 const (
-	allActivators                     = 0
+	allActivators = 0
+	// minActivators is the minimum number of activators a revision will get
+	// when burst capacity is configured.
+	minActivators = 2
+
 	KedaAutoscaleAnnotationAutocreate = autoscaling.GroupName + "/scaled-object-auto-create"
+
+	// PodAutoscalerConditionPrometheusQueryValid reports whether the queries of the
+	// prometheus triggers built from the PA annotations are valid PromQL.
+	PodAutoscalerConditionPrometheusQueryValid apis.ConditionType = "PrometheusQueryValid"
+
+	scaledObjectNotFoundReason     = "ScaledObjectNotFound"
+	waitingForHPAReason            = "WaitingForHPA"
+	hpaNotFoundReason              = "HPANotFound"
+	invalidQueryReason             = "InvalidPrometheusQuery"
+	invalidNamespaceDefaultsReason = "InvalidNamespaceDefaults"
+	defaultFallbackSkippedReason   = "DefaultFallbackSkipped"
+
+	// minHPARequeue and maxHPARequeue bound the backoff used while waiting
+	// for KEDA to create the HPA.
+	minHPARequeue = time.Second
+	maxHPARequeue = 30 * time.Second
+
+	// invalidQueryRequeue is how long to wait before checking an invalid
+	// prometheus query again, as triggers can also come from defaults that
+	// change independently of the revision.
+	invalidQueryRequeue = time.Minute
 )
 
 // Reconciler implements the control loop for the HPA resources.
@@ -59,6 +96,12 @@ type Reconciler struct {
 	kedaClient versioned.Interface
 	kedaLister kedav1alpha1.ScaledObjectLister
 	hpaLister  autoscalingv2listers.HorizontalPodAutoscalerLister
+
+	deploymentLister appsv1listers.DeploymentLister
+	namespaceLister  corev1listers.NamespaceLister
+	// eventingListers list the eventing sources the triggers of the revisions opting in
+	// are derived from, for the sources served by the cluster.
+	eventingListers []cache.GenericLister
 }
 
 // Check that our Reconciler implements pareconciler.Interface
@@ -73,6 +116,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *autoscalingv1alpha1.
 
 	var scaledObj *v1alpha1.ScaledObject
 	var hpa *v2.HorizontalPodAutoscaler
+	var queryErr *resources.QueryError
 	shouldCreateScaledObject := true
 
 	if hpaconfig.FromContext(ctx).AutoscalerKeda != nil {
@@ -88,38 +132,94 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *autoscalingv1alpha1.
 	}
 
 	if shouldCreateScaledObject {
-		dScaledObject, err := resources.DesiredScaledObject(ctx, pa)
-		if err != nil {
+		withDefaults := pa
+		if ns, err := c.namespaceLister.Get(pa.Namespace); err == nil {
+			withDefaults = resources.WithNamespaceDefaults(pa, ns)
+			reportInvalidNamespaceDefaults(ctx, pa, ns)
+		} else if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get namespace %q: %w", pa.Namespace, err)
+		}
+		var eventingTriggers []v1alpha1.ScaleTriggers
+		if resources.EventingTriggersEnabled(withDefaults) {
+			sources, err := c.eventingSources(pa.Namespace)
+			if err != nil {
+				return fmt.Errorf("failed to list eventing sources: %w", err)
+			}
+			var bootstrapServers string
+			if config := hpaconfig.FromContext(ctx).AutoscalerKeda; config != nil {
+				bootstrapServers = config.KafkaBootstrapServers
+			}
+			eventingTriggers = resources.EventingTriggers(withDefaults, sources, bootstrapServers)
+		}
+		dScaledObject, err := resources.DesiredScaledObject(ctx, withDefaults, eventingTriggers)
+		if goerrors.As(err, &queryErr) {
+			// The PA keeps scaling with its existing ScaledObject, if any, until the query is fixed.
+			markInvalidQuery(ctx, pa, queryErr)
+		} else if err != nil {
 			return fmt.Errorf("failed to contruct desiredScaledObject: %w", err)
+		} else if cond := pa.Status.GetCondition(PodAutoscalerConditionPrometheusQueryValid); cond != nil && cond.IsFalse() {
+			pa.GetConditionSet().Manage(&pa.Status).MarkTrue(PodAutoscalerConditionPrometheusQueryValid)
 		}
 
-		scaledObj, err = c.kedaLister.ScaledObjects(pa.Namespace).Get(dScaledObject.Name)
-		if errors.IsNotFound(err) {
+		scaledObj, err = c.kedaLister.ScaledObjects(pa.Namespace).Get(pa.Name)
+		if errors.IsNotFound(err) && queryErr != nil {
+			pa.Status.MarkInactive(invalidQueryReason, queryErr.Error())
+			return controller.NewRequeueAfter(invalidQueryRequeue)
+		} else if errors.IsNotFound(err) {
 			logger.Infof("Creating Scaled Object %q", dScaledObject.Name)
 			if scaledObj, err = c.kedaClient.KedaV1alpha1().ScaledObjects(dScaledObject.Namespace).Create(ctx, dScaledObject, metav1.CreateOptions{}); err != nil {
 				pa.Status.MarkResourceFailedCreation("ScaledObject", dScaledObject.Name)
 				return fmt.Errorf("failed to create ScaledObject: %w", err)
 			}
+			// The SKS is only reconciled once KEDA has created the HPA for the ScaledObject,
+			// so a PA with a service name had a ScaledObject that was deleted.
+			if pa.Status.ServiceName != "" {
+				controller.GetEventRecorder(ctx).Eventf(pa, corev1.EventTypeNormal, "ScaledObjectRecreated",
+					"Recreated deleted ScaledObject %q", dScaledObject.Name)
+			}
+			reportSkippedFallback(ctx, pa, dScaledObject)
 		} else if err != nil {
 			return fmt.Errorf("failed to get ScaledObject: %w", err)
 		} else if !metav1.IsControlledBy(scaledObj, pa) {
 			// Surface an error in the PodAutoscaler's status, and return an error.
-			pa.Status.MarkResourceNotOwned("ScaledObject", dScaledObject.Name)
-			return fmt.Errorf("PodAutoscaler: %q does not own ScaledObject: %q", pa.Name, dScaledObject.Name)
+			pa.Status.MarkResourceNotOwned("ScaledObject", scaledObj.Name)
+			return fmt.Errorf("PodAutoscaler: %q does not own ScaledObject: %q", pa.Name, scaledObj.Name)
 		}
-		if !equality.Semantic.DeepEqual(dScaledObject.Spec, scaledObj.Spec) {
+		if queryErr == nil && !equality.Semantic.DeepEqual(dScaledObject.Spec, scaledObj.Spec) {
 			logger.Infof("Updating ScaledObject %q", dScaledObject.Name)
 			update := scaledObj.DeepCopy()
 			update.Spec = dScaledObject.Spec
-			if _, err := c.kedaClient.KedaV1alpha1().ScaledObjects(pa.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+			if scaledObj, err = c.kedaClient.KedaV1alpha1().ScaledObjects(pa.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
 				return fmt.Errorf("failed to update ScaledObject: %w", err)
 			}
+			reportSkippedFallback(ctx, pa, dScaledObject)
+			controller.GetEventRecorder(ctx).Eventf(pa, corev1.EventTypeNormal, "ScaledObjectUpdated",
+				"Updated ScaledObject %q to the spec derived from the revision", dScaledObject.Name)
+		}
+	} else {
+		var err error
+		if scaledObj, err = c.findScaledObject(pa); err != nil {
+			return fmt.Errorf("failed to find ScaledObject: %w", err)
+		} else if scaledObj == nil {
+			// Further ScaledObject events eg. creation will trigger a new reconciliation.
+			pa.Status.MarkInactive(scaledObjectNotFoundReason, fmt.Sprintf(
+				"No ScaledObject targets deployment %q", pa.Spec.ScaleTargetRef.Name))
+			return nil
+		} else if !validateScaledObject(ctx, pa, scaledObj) {
+			pa.Status.MarkInactive(scaledObjectInvalidReason, fmt.Sprintf(
+				"ScaledObject %q does not target deployment %q", scaledObj.Name, pa.Spec.ScaleTargetRef.Name))
+			return nil
 		}
 	}
-	hpa, err := c.hpaLister.HorizontalPodAutoscalers(pa.Namespace).Get(pa.Name)
+
+	hpaName := scaledObjectHPAName(scaledObj)
+	hpa, err := c.hpaLister.HorizontalPodAutoscalers(pa.Namespace).Get(hpaName)
 	if errors.IsNotFound(err) {
-		logger.Infof("Skipping HPA %q", pa.Name)
-		return nil // skip, wait to be triggered by hpa events eg. creation
+		logger.Infof("Waiting for HPA %q", hpaName)
+		// Further HPA events eg. creation will trigger a new reconciliation.
+		return waitForHPA(ctx, pa, hpaName, scaledObj.Name)
+	} else if err != nil {
+		return fmt.Errorf("failed to get HPA: %w", err)
 	}
 
 	if scaledObj != nil && scaledObj.Spec.MinReplicaCount != nil {
@@ -128,7 +228,35 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *autoscalingv1alpha1.
 		}
 	}
 
-	sks, err := c.ReconcileSKS(ctx, pa, nv1alpha1.SKSOperationModeServe, allActivators)
+	scaleToZero := scaleToZeroEnabled(ctx, pa, scaledObj)
+	want, ready, err := c.scaleTargetReplicas(pa)
+	if err != nil {
+		return fmt.Errorf("error getting scale target replicas: %w", err)
+	}
+
+	asConfig := hpaconfig.FromContext(ctx).Autoscaler
+	tbc := targetBurstCapacity(asConfig, pa)
+	excessBC, numActivators := float64(0), int32(allActivators)
+	if target, total, ok := resolveCapacity(asConfig, pa); ok {
+		excessBC = excessBurstCapacity(ready, hpa.Status.DesiredReplicas, tbc, target, total)
+		numActivators = computeNumActivators(ready, tbc, total, asConfig.ActivatorCapacity)
+	} else if tbc < 0 {
+		// The request load of the revision is unknown, so only an unlimited
+		// burst capacity puts the activator in the request path.
+		excessBC = -1
+	}
+	logger.Debugf("Ready pods: %d, desired pods: %d, excess burst capacity: %v", ready, hpa.Status.DesiredReplicas, excessBC)
+
+	mode := nv1alpha1.SKSOperationModeServe
+	// We put the activator in the request path in the following cases:
+	// 1. The revision can scale to zero and has no ready pods, so requests
+	//    are buffered while KEDA activates the scale target.
+	// 2. The excess burst capacity is negative.
+	if scaleToZero && ready == 0 || excessBC < 0 {
+		mode = nv1alpha1.SKSOperationModeProxy
+	}
+
+	sks, err := c.ReconcileSKS(ctx, pa, mode, numActivators)
 	if err != nil {
 		return fmt.Errorf("error reconciling SKS: %w", err)
 	}
@@ -152,13 +280,256 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *autoscalingv1alpha1.
 		}
 	}
 
-	pa.Status.MarkActive()
+	switch {
+	case !scaleToZero || ready > 0:
+		pa.Status.MarkActive()
+	case want == 0:
+		pa.Status.MarkInactive("NoTraffic", "The target is not receiving traffic.")
+	default:
+		pa.Status.MarkActivating("Queued", "Requests to the target are being buffered as resources are provisioned.")
+	}
+	propagateScaledObjectStatus(pa, scaledObj)
+	propagateTriggerHealth(ctx, pa, scaledObj)
 
-	pa.Status.DesiredScale = ptr.Int32(hpa.Status.DesiredReplicas)
-	pa.Status.ActualScale = ptr.Int32(hpa.Status.CurrentReplicas)
+	// The HPA never reports less than one replica, so the scale of a target
+	// deactivated by KEDA is taken from the deployment.
+	desired, actual := hpa.Status.DesiredReplicas, hpa.Status.CurrentReplicas
+	if want == 0 {
+		desired, actual = 0, ready
+	}
+	pa.Status.DesiredScale = ptr.Int32(desired)
+	pa.Status.ActualScale = ptr.Int32(actual)
+	if queryErr != nil {
+		return controller.NewRequeueAfter(invalidQueryRequeue)
+	}
 	return nil
 }
 
+// markInvalidQuery reports a prometheus trigger query that KEDA would fail to evaluate
+// in the PrometheusQueryValid condition of the PA and as a warning event.
+func markInvalidQuery(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, queryErr *resources.QueryError) {
+	manager := pa.GetConditionSet().Manage(&pa.Status)
+	message := queryErr.Error()
+	prev := manager.GetCondition(PodAutoscalerConditionPrometheusQueryValid)
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil && (prev == nil || !prev.IsFalse() || prev.Message != message) {
+		recorder.Event(pa, corev1.EventTypeWarning, invalidQueryReason, message)
+	}
+	manager.MarkFalse(PodAutoscalerConditionPrometheusQueryValid, invalidQueryReason, "%s", message)
+}
+
+// reportInvalidNamespaceDefaults emits a warning event on the PA if the defaults of its
+// namespace are invalid, as namespaces are not validated on admission.
+func reportInvalidNamespaceDefaults(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, ns *corev1.Namespace) {
+	if err := resources.ValidateAnnotations(resources.NamespaceDefaults(ns)); err != nil {
+		if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+			recorder.Eventf(pa, corev1.EventTypeWarning, invalidNamespaceDefaultsReason,
+				"Invalid autoscaling defaults on namespace %q: %v", ns.Name, err)
+		}
+	}
+}
+
+// reportSkippedFallback emits a warning event on the PA if the fallback defaulted in the
+// autoscaler keda ConfigMap was not set on its ScaledObject, as KEDA does not support it
+// for the triggers of the revision.
+func reportSkippedFallback(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) {
+	config := hpaconfig.FromContext(ctx).AutoscalerKeda
+	if config == nil || config.Fallback == nil || scaledObj.Spec.Fallback != nil {
+		return
+	}
+	withFallback := scaledObj.DeepCopy()
+	withFallback.Spec.Fallback = config.Fallback.DeepCopy()
+	if err := v1alpha1.CheckFallbackValid(withFallback); err != nil {
+		if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+			recorder.Eventf(pa, corev1.EventTypeWarning, defaultFallbackSkippedReason,
+				"The default fallback is not set on ScaledObject %q: %v", scaledObj.Name, err)
+		}
+	}
+}
+
+// findScaledObject returns the ScaledObject brought by the user to scale the PA's
+// scale target, or nil if there is none. If several ScaledObjects target the
+// deployment, the first one by name is returned. If none does, the ScaledObject
+// named after the revision is returned, so that one whose target was not updated
+// can be reported.
+func (c *Reconciler) findScaledObject(pa *autoscalingv1alpha1.PodAutoscaler) (*v1alpha1.ScaledObject, error) {
+	scaledObjs, err := c.kedaLister.ScaledObjects(pa.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var found *v1alpha1.ScaledObject
+	for _, so := range scaledObjs {
+		if targetsDeployment(so, pa.Spec.ScaleTargetRef.Name) && (found == nil || so.Name < found.Name) {
+			found = so
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	found, err = c.kedaLister.ScaledObjects(pa.Namespace).Get(pa.Name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return found, err
+}
+
+// targetsDeployment returns true if the ScaledObject scales the named deployment.
+func targetsDeployment(so *v1alpha1.ScaledObject, name string) bool {
+	ref := so.Spec.ScaleTargetRef
+	return ref != nil && ref.Name == name && (ref.Kind == "" || ref.Kind == "Deployment")
+}
+
+// scaledObjectHPAName returns the name of the HPA KEDA created, or is about to
+// create, for the ScaledObject.
+func scaledObjectHPAName(so *v1alpha1.ScaledObject) string {
+	if so.Status.HpaName != "" {
+		return so.Status.HpaName
+	}
+	if adv := so.Spec.Advanced; adv != nil && adv.HorizontalPodAutoscalerConfig != nil &&
+		adv.HorizontalPodAutoscalerConfig.Name != "" {
+		return adv.HorizontalPodAutoscalerConfig.Name
+	}
+	// The default name given by KEDA.
+	return "keda-hpa-" + so.Name
+}
+
+// waitForHPA reports that KEDA has not created the HPA for the ScaledObject yet and
+// requeues the PA with a bounded backoff. Once the HPA creation deadline has passed
+// the PA is marked inactive.
+func waitForHPA(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, hpaName, soName string) error {
+	cond := pa.Status.GetCondition(autoscalingv1alpha1.PodAutoscalerConditionActive)
+	if pa.Status.IsInactive() && cond.Reason == hpaNotFoundReason {
+		// The deadline has already passed, stay failed until the HPA shows up.
+		return nil
+	}
+
+	deadline := hpaCreationDeadline(ctx, pa)
+	var waited time.Duration
+	if pa.Status.IsActivating() && cond.Reason == waitingForHPAReason {
+		waited = time.Since(cond.LastTransitionTime.Inner.Time)
+	}
+	if waited >= deadline {
+		pa.Status.MarkInactive(hpaNotFoundReason, fmt.Sprintf(
+			"KEDA did not create HPA %q for ScaledObject %q within %v", hpaName, soName, deadline))
+		return nil
+	}
+
+	pa.Status.MarkActivating(waitingForHPAReason, fmt.Sprintf(
+		"Waiting for KEDA to create HPA %q for ScaledObject %q", hpaName, soName))
+	// Back off proportionally to the time already spent waiting, without
+	// overshooting the deadline.
+	backoff := min(max(waited, minHPARequeue), maxHPARequeue, deadline-waited)
+	return controller.NewRequeueAfter(backoff)
+}
+
+// hpaCreationDeadline returns how long to wait for the HPA from the revision's
+// progress deadline or the autoscaler keda ConfigMap.
+func hpaCreationDeadline(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler) time.Duration {
+	if pd, ok := pa.ProgressDeadline(); ok {
+		return pd
+	}
+	if kedaConfig := hpaconfig.FromContext(ctx).AutoscalerKeda; kedaConfig != nil {
+		return kedaConfig.HPACreationDeadline
+	}
+	return hpaconfig.DefaultHPACreationDeadline
+}
+
+// scaleTargetReplicas returns the desired and ready replicas of the PA's scale target.
+// A scale target that does not exist yet is reported as having no replicas.
+func (c *Reconciler) scaleTargetReplicas(pa *autoscalingv1alpha1.PodAutoscaler) (int32, int32, error) {
+	deployment, err := c.deploymentLister.Deployments(pa.Namespace).Get(pa.Spec.ScaleTargetRef.Name)
+	if errors.IsNotFound(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	want := int32(1)
+	if deployment.Spec.Replicas != nil {
+		want = *deployment.Spec.Replicas
+	}
+	return want, deployment.Status.ReadyReplicas, nil
+}
+
+// eventingSources returns the eventing sources of the namespace, the triggers of the
+// revisions opting in are derived from those whose sink is their Service.
+func (c *Reconciler) eventingSources(namespace string) ([]*unstructured.Unstructured, error) {
+	var sources []*unstructured.Unstructured
+	for _, lister := range c.eventingListers {
+		objs, err := lister.ByNamespace(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if source, ok := obj.(*unstructured.Unstructured); ok {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// targetBurstCapacity returns the burst capacity of the revision from the PA annotation
+// or the autoscaler ConfigMap.
+func targetBurstCapacity(asConfig *autoscalerconfig.Config, pa *autoscalingv1alpha1.PodAutoscaler) float64 {
+	if tbc, ok := pa.TargetBC(); ok {
+		return tbc
+	}
+	return asConfig.TargetBurstCapacity
+}
+
+// resolveCapacity returns the per pod target and total request capacity of the revision,
+// or false if its metric does not measure requests. The request capacity of a pod cannot be
+// derived from cpu, memory or custom metrics, so their burst capacity is not computed.
+func resolveCapacity(asConfig *autoscalerconfig.Config, pa *autoscalingv1alpha1.PodAutoscaler) (float64, float64, bool) {
+	switch pa.Metric() {
+	case autoscaling.Concurrency, autoscaling.RPS:
+		target, total := aresources.ResolveMetricTarget(pa, asConfig)
+		return target, total, true
+	}
+	return 0, 0, false
+}
+
+// excessBurstCapacity returns the capacity left on the ready pods after serving the load
+// the HPA is scaling for and the requested burst capacity.
+// As the HPA scales to keep the load per pod at the target, the current load is
+// approximated by the desired replicas times the per pod target.
+func excessBurstCapacity(ready, desired int32, tbc, target, total float64) float64 {
+	switch {
+	case tbc == 0:
+		return 0
+	case tbc < 0:
+		// Unlimited burst capacity, always keep the activator in the path.
+		return -1
+	}
+	return math.Floor(float64(ready)*total - float64(desired)*target - tbc)
+}
+
+// computeNumActivators returns the number of activators the SKS should use
+// to cover the burst capacity on top of the ready pods.
+func computeNumActivators(ready int32, tbc, total, activatorCapacity float64) int32 {
+	if tbc == 0 {
+		return allActivators
+	}
+	capacityToCover := float64(ready) * total
+	if tbc > 0 {
+		capacityToCover += tbc
+	}
+	return int32(math.Max(minActivators, math.Ceil(capacityToCover/activatorCapacity)))
+}
+
+// scaleToZeroEnabled returns true if the revision is allowed to scale to zero and the
+// ScaledObject driving it lets KEDA deactivate the scale target.
+func scaleToZeroEnabled(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) bool {
+	asConfig := hpaconfig.FromContext(ctx).Autoscaler
+	if !asConfig.EnableScaleToZero {
+		return false
+	}
+	if minScale, _ := pa.ScaleBounds(asConfig); minScale > 0 {
+		return false
+	}
+	// KEDA defaults to a minimum of zero replicas when none is specified.
+	return scaledObj != nil && (scaledObj.Spec.MinReplicaCount == nil || *scaledObj.Spec.MinReplicaCount == 0)
+}
+
 // activeThreshold returns the scale required for the pa to be marked Active
 func activeThreshold(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler) int {
 	asConfig := hpaconfig.FromContext(ctx).Autoscaler
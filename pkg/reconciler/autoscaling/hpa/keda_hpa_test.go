@@ -19,6 +19,7 @@ package hpa
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -27,15 +28,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ktesting "k8s.io/client-go/testing"
 
 	kedav1alpha1 "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	fakekedaclientset "github.com/kedacore/keda/v2/pkg/generated/clientset/versioned/fake"
 	fakekedaclient "knative.dev/autoscaler-keda/pkg/client/injection/client/fake"
 	kedaresources "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/resources"
 	nv1a1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	networkingclient "knative.dev/networking/pkg/client/injection/client"
+	"knative.dev/pkg/apis"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
@@ -45,6 +49,7 @@ import (
 	"knative.dev/pkg/system"
 	"knative.dev/serving/pkg/apis/autoscaling"
 	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	autoscalerconfig "knative.dev/serving/pkg/autoscaler/config"
 	servingclient "knative.dev/serving/pkg/client/injection/client"
@@ -59,7 +64,9 @@ import (
 	_ "knative.dev/autoscaler-keda/pkg/client/injection/informers/keda/v1alpha1/scaledobject"
 	_ "knative.dev/autoscaler-keda/pkg/client/injection/informers/keda/v1alpha1/scaledobject/fake"
 	_ "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/serverlessservice/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/autoscaling/v2/horizontalpodautoscaler/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/service/fake"
 	_ "knative.dev/serving/pkg/client/injection/ducks/autoscaling/v1alpha1/podscalable/fake"
 	_ "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/metric/fake"
@@ -107,8 +114,9 @@ func TestControllerCanReconcile(t *testing.T) {
 		la.Promote(reconciler.UniversalBucket(), func(reconciler.Bucket, types.NamespacedName) {})
 	}
 
+	// KEDA is not running, so the PA is requeued while waiting for the HPA.
 	err = ctl.Reconciler.Reconcile(ctx, helpers.TestNamespace+"/"+helpers.TestRevision)
-	if err != nil {
+	if requeue, _ := controller.IsRequeueKey(err); err != nil && !requeue {
 		t.Error("Reconcile() =", err)
 	}
 
@@ -119,13 +127,16 @@ func TestControllerCanReconcile(t *testing.T) {
 }
 
 func TestReconcile(t *testing.T) {
+	namespaceExtraTriggers := `[{"name": "queue", "type": "prometheus", "metadata": {"query": "sum(queue_length)", "threshold": "10"}}]`
 	retryAttempted := false
 	deployName := helpers.TestRevision + "-deployment"
 	privateSvc := names.PrivateService(helpers.TestRevision)
+	invalidQueryMessage := `invalid prometheus query "sum(up" for trigger "default-trigger-custom": 1:7: parse error: unclosed left parenthesis`
 
 	table := reconcilertesting.TableTest{{
 		Name: "no op",
 		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithPASKSReady, WithTraffic, WithScaleTargetInitialized,
 				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc), withScales(0, 0)),
@@ -153,6 +164,7 @@ func TestReconcile(t *testing.T) {
 		},
 		WantCreates: []runtime.Object{
 			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
 			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withScales(1, 0),
@@ -176,9 +188,13 @@ func TestReconcile(t *testing.T) {
 				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
 		}},
 		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 	}, {
 		Name: "reconcile sks becomes ready, scale target not initialized",
 		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithPASKSNotReady("I wasn't ready yet :-("),
 				WithMetricAnnotation("cpu")), withHPADesiredReplicas(1)),
 			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithPAStatusService("the-wrong-one")),
@@ -205,6 +221,9 @@ func TestReconcile(t *testing.T) {
 				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
 		}},
 		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 		WantUpdates: []ktesting.UpdateActionImpl{{
 			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
 		}},
@@ -222,6 +241,9 @@ func TestReconcile(t *testing.T) {
 				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
 		}},
 		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 		WantUpdates: []ktesting.UpdateActionImpl{{
 			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
 		}},
@@ -240,6 +262,9 @@ func TestReconcile(t *testing.T) {
 				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
 		}},
 		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 		WantUpdates: []ktesting.UpdateActionImpl{{
 			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName),
 				WithPubService, WithPrivateService),
@@ -253,6 +278,9 @@ func TestReconcile(t *testing.T) {
 			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPADesiredReplicas(1)),
 		},
 		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 		WithReactors: []ktesting.ReactionFunc{
 			reconcilertesting.InduceFailure("update", "serverlessservices"),
 		},
@@ -277,6 +305,7 @@ func TestReconcile(t *testing.T) {
 		WantErr: true,
 		WantCreates: []runtime.Object{
 			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		WantEvents: []string{
 			reconcilertesting.Eventf(corev1.EventTypeWarning, "InternalError", "error reconciling SKS: error creating SKS test-revision: inducing failure for create serverlessservices"),
@@ -289,7 +318,10 @@ func TestReconcile(t *testing.T) {
 			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSOwnersRemoved, WithSKSReady),
 			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPADesiredReplicas(1)),
 		},
-		Key:     key(helpers.TestNamespace, helpers.TestRevision),
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
 		WantErr: true,
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
 			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, MarkResourceNotOwnedByPA("ServerlessService", helpers.TestRevision)),
@@ -325,6 +357,7 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "update pa fails",
 		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(19, 18)),
 			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithPAStatusService("the-wrong-one"), withScales(42, 84)),
 			deploy(helpers.TestNamespace, helpers.TestRevision),
@@ -343,6 +376,606 @@ func TestReconcile(t *testing.T) {
 		WantEvents: []string{
 			reconcilertesting.Eventf(corev1.EventTypeWarning, "UpdateFailed", `Failed to update status for "test-revision": inducing failure for update podautoscalers`),
 		},
+	}, {
+		Name: "scale to zero, scaled to zero",
+		Objects: []runtime.Object{
+			// The HPA keeps reporting one replica while KEDA has deactivated the deployment.
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(0, 0)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady, WithProxyMode),
+		}},
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric,
+				WithNoTraffic("NoTraffic", "The target is not receiving traffic."),
+				WithScaleTargetInitialized, withScales(0, 0), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+		}},
+	}, {
+		Name: "scale to zero, activating from zero",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric,
+				WithNoTraffic("NoTraffic", "The target is not receiving traffic."),
+				WithScaleTargetInitialized, withScales(0, 0), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 0)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady, WithProxyMode),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric,
+				WithBufferedTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+		}},
+	}, {
+		Name: "scale to zero, pods ready after activation",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric,
+				WithBufferedTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady, WithProxyMode),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		}},
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric,
+				WithTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+		}},
+	}, {
+		Name: "scale to zero, min scale set",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1),
+				WithTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 0)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "burst capacity, not enough capacity",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric)),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric), withHPAScaleStatus(2, 2)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric, withTBC(200), WithTraffic,
+				WithScaleTargetInitialized, withScales(2, 2), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(2, 2)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			// 2 pods * 100 - 2 pods * 70 - 200 < 0, and (2 * 100 + 200) / 100 activators.
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithProxyMode, WithNumActivators(4)),
+		}},
+	}, {
+		Name: "burst capacity, enough capacity",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric)),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric), withHPAScaleStatus(2, 10)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric, withTBC(200), WithTraffic,
+				WithScaleTargetInitialized, withScales(2, 10), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(10, 10)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithProxyMode, WithNumActivators(4)),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			// 10 pods * 100 - 2 pods * 70 - 200 >= 0, and (10 * 100 + 200) / 100 activators.
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithNumActivators(12)),
+		}},
+	}, {
+		Name: "burst capacity, unlimited",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric)),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric), withHPAScaleStatus(1, 1)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric, withTBC(-1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithProxyMode, WithNumActivators(minActivators)),
+		}},
+	}, {
+		Name: "burst capacity, default",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric)),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric), withHPAScaleStatus(1, 1)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withConcurrencyMetric, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			// 1 pod * 100 - 1 pod * 70 - 211 < 0, and (1 * 100 + 211) / 100 activators.
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithProxyMode, WithNumActivators(4)),
+		}},
+	}, {
+		Name: "burst capacity, cpu metric",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(2, 2)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"), withTBC(200), WithTraffic,
+				WithScaleTargetInitialized, withScales(2, 2), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(2, 2)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		// The request capacity of the pods is unknown, so the activator stays out of the path.
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "burst capacity, unlimited with cpu metric",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"), withTBC(-1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady,
+				WithProxyMode, WithNumActivators(allActivators)),
+		}},
+	}, {
+		Name: "propagate scaled object conditions",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectConditions(
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionReady, Status: metav1.ConditionTrue,
+						Reason: kedav1alpha1.ScaledObjectConditionReadySuccessReason, Message: kedav1alpha1.ScaledObjectConditionReadySuccessMessage},
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionActive, Status: metav1.ConditionTrue, Reason: "ScalerActive"},
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionFallback, Status: metav1.ConditionFalse, Reason: "NoFallbackFound"},
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionPaused, Status: metav1.ConditionUnknown})),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionScaledObjectReady, corev1.ConditionTrue,
+					kedav1alpha1.ScaledObjectConditionReadySuccessReason, kedav1alpha1.ScaledObjectConditionReadySuccessMessage),
+				withPACondition(PodAutoscalerConditionScaledObjectActive, corev1.ConditionTrue, "ScalerActive", ""),
+				withPACondition(PodAutoscalerConditionScaledObjectFallback, corev1.ConditionFalse, "NoFallbackFound", ""),
+				withPACondition(PodAutoscalerConditionScaledObjectPaused, corev1.ConditionUnknown, "", "")),
+		}},
+	}, {
+		Name: "scaled object not ready",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectConditions(
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionReady, Status: metav1.ConditionFalse,
+						Reason: "ScaledObjectCheckFailed", Message: "failed to ensure HPA is correctly created for ScaledObject"},
+					kedav1alpha1.Condition{Type: kedav1alpha1.ConditionFallback, Status: metav1.ConditionTrue,
+						Reason: "FallbackExists", Message: "At least one trigger is falling back on this scaled object"})),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionScaledObjectReady, corev1.ConditionFalse,
+					"ScaledObjectCheckFailed", "failed to ensure HPA is correctly created for ScaledObject"),
+				withPACondition(PodAutoscalerConditionScaledObjectFallback, corev1.ConditionTrue,
+					"FallbackExists", "At least one trigger is falling back on this scaled object"),
+				withPAActivating("ScaledObjectCheckFailed", "failed to ensure HPA is correctly created for ScaledObject")),
+		}},
+	}, {
+		Name: "trigger health failing",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)),
+				withScaledObjectHealth("s0-prometheus", kedav1alpha1.HealthStatusFailing, 3)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionFalse,
+					"TriggerFailing", `trigger "default-trigger-custom" failed 3 consecutive times`)),
+		}},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "TriggerFailing",
+				`ScaledObject "test-revision": trigger "default-trigger-custom" failed 3 consecutive times`),
+		},
+	}, {
+		Name: "trigger health still failing",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)),
+				withScaledObjectHealth("s0-prometheus", kedav1alpha1.HealthStatusFailing, 3)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionFalse,
+					"TriggerFailing", `trigger "default-trigger-custom" failed 3 consecutive times`)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "trigger health recovered",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)),
+				withScaledObjectHealth("s0-prometheus", kedav1alpha1.HealthStatusHappy, 0)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionFalse,
+					"TriggerFailing", `trigger "default-trigger-custom" failed 3 consecutive times`)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionTrue, "", "")),
+		}},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeNormal, "TriggersRecovered",
+				`All triggers of ScaledObject "test-revision" are healthy`),
+		},
+	}, {
+		Name: "trigger health no longer reported",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1)), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionFalse,
+					"TriggerFailing", `trigger "default-trigger-custom" failed 3 consecutive times`)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withMinScale(1), WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionTriggersHealthy, corev1.ConditionUnknown,
+					"TriggersHealthUnknown", `KEDA reports no health for the triggers of ScaledObject "test-revision"`)),
+		}},
+	}, {
+		Name: "scaled object deleted",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeNormal, "ScaledObjectRecreated", `Recreated deleted ScaledObject "test-revision"`),
+		},
+	}, {
+		Name: "default fallback not supported by the triggers",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		OtherTestData: map[string]interface{}{
+			testConfigKey: configWithFallback(),
+		},
+		WantCreates: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeNormal, "ScaledObjectRecreated", `Recreated deleted ScaledObject "test-revision"`),
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "DefaultFallbackSkipped",
+				`The default fallback is not set on ScaledObject "test-revision": type is cpu , but fallback it is not supported by the CPU & memory scalers`),
+		},
+	}, {
+		Name: "scaled object modified",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				func(scaledObj *kedav1alpha1.ScaledObject) {
+					scaledObj.Spec.MaxReplicaCount = ptr.Int32(3)
+				}),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+		}},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeNormal, "ScaledObjectUpdated", `Updated ScaledObject "test-revision" to the spec derived from the revision`),
+		},
+	}, {
+		Name: "namespace defaults changed",
+		Objects: []runtime.Object{
+			&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: helpers.TestNamespace,
+					Annotations: map[string]string{
+						kedaresources.KedaAutoscaleAnnotationExtraPrometheusTriggers: namespaceExtraTriggers,
+					},
+				},
+			},
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantUpdates: []ktesting.UpdateActionImpl{{
+			Object: scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				func(scaledObj *kedav1alpha1.ScaledObject) {
+					scaledObj.Spec = scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+						helpers.WithAnnotations(map[string]string{kedaresources.KedaAutoscaleAnnotationExtraPrometheusTriggers: namespaceExtraTriggers}))).Spec
+				}),
+		}},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeNormal, "ScaledObjectUpdated", `Updated ScaledObject "test-revision" to the spec derived from the revision`),
+		},
+	}, {
+		Name: "namespace defaults unchanged",
+		Objects: []runtime.Object{
+			&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: helpers.TestNamespace,
+					Annotations: map[string]string{
+						kedaresources.KedaAutoscaleAnnotationExtraPrometheusTriggers: namespaceExtraTriggers,
+					},
+				},
+			},
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				helpers.WithAnnotations(map[string]string{kedaresources.KedaAutoscaleAnnotationExtraPrometheusTriggers: namespaceExtraTriggers}))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "invalid namespace defaults",
+		Objects: []runtime.Object{
+			&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: helpers.TestNamespace,
+					Annotations: map[string]string{
+						kedaresources.KedaAutoscaleAnnotationPrometheusAddress: "prometheus operated",
+					},
+				},
+			},
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithTraffic,
+				WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "InvalidNamespaceDefaults",
+				"Invalid autoscaling defaults on namespace %q: %s", helpers.TestNamespace,
+				"invalid value: prometheus operated: autoscaling.knative.dev/prometheus-address\nparse \"prometheus operated\": invalid URI for request"),
+		},
+	}, {
+		Name: "waiting for hpa",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		// The PA is requeued until the HPA is created.
+		WantErr: true,
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				withPAActivating("WaitingForHPA", `Waiting for KEDA to create HPA "test-revision" for ScaledObject "test-revision"`)),
+		}},
+	}, {
+		Name: "hpa creation deadline exceeded",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				withPAActivating("WaitingForHPA", `Waiting for KEDA to create HPA "test-revision" for ScaledObject "test-revision"`),
+				withActiveSince(time.Now().Add(-11*time.Minute))),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				WithNoTraffic("HPANotFound", `KEDA did not create HPA "test-revision" for ScaledObject "test-revision" within 10m0s`)),
+		}},
+	}, {
+		Name: "hpa creation deadline from progress deadline",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				withProgressDeadline("30s"),
+				withPAActivating("WaitingForHPA", `Waiting for KEDA to create HPA "test-revision" for ScaledObject "test-revision"`),
+				withActiveSince(time.Now().Add(-time.Minute))),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				withProgressDeadline("30s"),
+				WithNoTraffic("HPANotFound", `KEDA did not create HPA "test-revision" for ScaledObject "test-revision" within 30s`)),
+		}},
+	}, {
+		Name: "hpa creation deadline already exceeded",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu"),
+				WithNoTraffic("HPANotFound", `KEDA did not create HPA "test-revision" for ScaledObject "test-revision" within 10m0s`)),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "bring your own scaled object, hpa named by keda",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectOwnersRemoved, withScaledObjectName("user-scaledobject"), withScaledObjectHPAName("keda-hpa-user-scaledobject")),
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withHPAName("keda-hpa-user-scaledobject")),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn, withPAScaledObjectValid, WithPASKSReady,
+				WithTraffic, WithScaleTargetInitialized, WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc), withScales(0, 0)),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+	}, {
+		Name: "bring your own scaled object, waiting for hpa",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectOwnersRemoved, withScaledObjectName("user-scaledobject"), func(scaledObj *kedav1alpha1.ScaledObject) {
+					scaledObj.Spec.Advanced = nil
+				}),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key:     key(helpers.TestNamespace, helpers.TestRevision),
+		WantErr: true,
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn, withPAScaledObjectValid,
+				withPAActivating("WaitingForHPA", `Waiting for KEDA to create HPA "keda-hpa-user-scaledobject" for ScaledObject "user-scaledobject"`)),
+		}},
+	}, {
+		Name: "bring your own scaled object, not found",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, "other-revision", WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectOwnersRemoved),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn,
+				WithNoTraffic("ScaledObjectNotFound", `No ScaledObject targets deployment "test-revision-deployment"`)),
+		}},
+	}, {
+		Name: "bring your own scaled object, wrong scale target",
+		Objects: []runtime.Object{
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")),
+				withScaledObjectOwnersRemoved, func(scaledObj *kedav1alpha1.ScaledObject) {
+					scaledObj.Spec.ScaleTargetRef.Name = "previous-revision-deployment"
+				}),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withBringYourOwn,
+				withPACondition(PodAutoscalerConditionScaledObjectValid, corev1.ConditionFalse, "ScaledObjectInvalid",
+					`ScaledObject "test-revision": scaleTargetRef does not point at deployment "test-revision-deployment"`),
+				WithNoTraffic("ScaledObjectInvalid", `ScaledObject "test-revision" does not target deployment "test-revision-deployment"`)),
+		}},
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "ScaledObjectInvalid",
+				`ScaledObject "test-revision": scaleTargetRef does not point at deployment "test-revision-deployment"`),
+		},
+	}, {
+		Name: "invalid prometheus query",
+		Objects: []runtime.Object{
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withPrometheusQuery("sum(up")),
+			deploy(helpers.TestNamespace, helpers.TestRevision),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withPrometheusQuery("sum(up"),
+				withPACondition(PodAutoscalerConditionPrometheusQueryValid, corev1.ConditionFalse, "InvalidPrometheusQuery", invalidQueryMessage),
+				WithNoTraffic("InvalidPrometheusQuery", invalidQueryMessage)),
+		}},
+		// The PA is requeued in case the query is fixed by defaults.
+		WantErr: true,
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "InvalidPrometheusQuery", invalidQueryMessage),
+		},
+	}, {
+		Name: "invalid prometheus query, existing scaled object kept",
+		Objects: []runtime.Object{
+			hpa(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass), withHPAScaleStatus(1, 1)),
+			scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass)),
+			helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withPrometheusQuery("sum(up"),
+				WithTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc)),
+			deploy(helpers.TestNamespace, helpers.TestRevision, withDeployReplicas(1, 1)),
+			sks(helpers.TestNamespace, helpers.TestRevision, WithDeployRef(deployName), WithSKSReady),
+		},
+		Key: key(helpers.TestNamespace, helpers.TestRevision),
+		WantStatusUpdates: []ktesting.UpdateActionImpl{{
+			Object: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withPrometheusMetric, withPrometheusQuery("sum(up"),
+				WithTraffic, WithScaleTargetInitialized, withScales(1, 1), WithPASKSReady,
+				WithPAStatusService(helpers.TestRevision), WithPAMetricsService(privateSvc),
+				withPACondition(PodAutoscalerConditionPrometheusQueryValid, corev1.ConditionFalse, "InvalidPrometheusQuery", invalidQueryMessage)),
+		}},
+		WantErr: true,
+		WantEvents: []string{
+			reconcilertesting.Eventf(corev1.EventTypeWarning, "InvalidPrometheusQuery", invalidQueryMessage),
+		},
 	}, {
 		Name: "invalid key",
 		Objects: []runtime.Object{
@@ -351,10 +984,17 @@ func TestReconcile(t *testing.T) {
 		Key: "sandwich///",
 	}}
 
-	table.Test(t, testingv1.MakeFactory(func(ctx context.Context, listers *testingv1.Listers, _ configmap.Watcher) controller.Reconciler {
+	var kedaClient *fakekedaclientset.Clientset
+	var rowConfig *hpaconfig.Config
+	factory := testingv1.MakeFactory(func(ctx context.Context, listers *testingv1.Listers, _ configmap.Watcher) controller.Reconciler {
 		retryAttempted = false
 		ctx = podscalable.WithDuck(ctx)
-		ctx, _ = fakekedaclient.With(ctx)
+		scaledObjects, _ := listers.GetKedaLister().List(labels.Everything())
+		kedaObjects := make([]runtime.Object, 0, len(scaledObjects))
+		for _, so := range scaledObjects {
+			kedaObjects = append(kedaObjects, so)
+		}
+		ctx, kedaClient = fakekedaclient.With(ctx, kedaObjects...)
 
 		r := &Reconciler{
 			Base: &areconciler.Base{
@@ -367,13 +1007,27 @@ func TestReconcile(t *testing.T) {
 			hpaLister:  listers.GetHorizontalPodAutoscalerLister(),
 			kedaLister: listers.GetKedaLister(),
 			kedaClient: fakekedaclient.Get(ctx),
+
+			deploymentLister: listers.GetDeploymentLister(),
+			namespaceLister:  listers.GetNamespaceLister(),
 		}
 		return pareconciler.NewReconciler(ctx, logging.FromContext(ctx), servingclient.Get(ctx),
 			listers.GetPodAutoscalerLister(), controller.GetEventRecorder(ctx), r, autoscaling.HPA,
 			controller.Options{
-				ConfigStore: &testConfigStore{config: defaultConfig()},
+				ConfigStore: &testConfigStore{config: rowConfig},
 			})
-	}))
+	})
+
+	// Record the actions of the KEDA client as well, so that the rows assert the
+	// ScaledObjects created and updated by the reconciler.
+	table.Test(t, func(t *testing.T, r *reconcilertesting.TableRow) (controller.Reconciler, reconcilertesting.ActionRecorderList, reconcilertesting.EventList) {
+		rowConfig = defaultConfig()
+		if config, ok := r.OtherTestData[testConfigKey].(*hpaconfig.Config); ok {
+			rowConfig = config
+		}
+		c, actionRecorders, events := factory(t, r)
+		return c, append(actionRecorders, kedaClient), events
+	})
 }
 
 func sks(ns, n string, so ...SKSOption) *nv1a1.ServerlessService {
@@ -396,6 +1050,56 @@ func withScales(d, a int32) PodAutoscalerOption {
 		pa.Status.DesiredScale, pa.Status.ActualScale = ptr.Int32(d), ptr.Int32(a)
 	}
 }
+func withPACondition(t apis.ConditionType, status corev1.ConditionStatus, reason, message string) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		manager := pa.GetConditionSet().Manage(&pa.Status)
+		switch status {
+		case corev1.ConditionTrue:
+			manager.MarkTrueWithReason(t, reason, message)
+		case corev1.ConditionFalse:
+			manager.MarkFalse(t, reason, message)
+		default:
+			manager.MarkUnknown(t, reason, message)
+		}
+	}
+}
+
+func withPAScaledObjectValid(pa *autoscalingv1alpha1.PodAutoscaler) {
+	pa.GetConditionSet().Manage(&pa.Status).MarkTrue(PodAutoscalerConditionScaledObjectValid)
+}
+
+func withPAActivating(reason, message string) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		pa.Status.MarkActivating(reason, message)
+	}
+}
+
+func withActiveSince(t time.Time) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		for i := range pa.Status.Conditions {
+			if pa.Status.Conditions[i].Type == autoscalingv1alpha1.PodAutoscalerConditionActive {
+				pa.Status.Conditions[i].LastTransitionTime = apis.VolatileTime{Inner: metav1.NewTime(t)}
+			}
+		}
+	}
+}
+
+func withProgressDeadline(pd string) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		pa.Annotations[serving.ProgressDeadlineAnnotationKey] = pd
+	}
+}
+
+func withHPAName(name string) hpaOption {
+	return func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+		hpa.Name = name
+	}
+}
+
+func withBringYourOwn(pa *autoscalingv1alpha1.PodAutoscaler) {
+	pa.Annotations[KedaAutoscaleAnnotationAutocreate] = "false"
+}
+
 func withHPAScaleStatus(d, a int32) hpaOption {
 	return func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
 		hpa.Status.DesiredReplicas, hpa.Status.CurrentReplicas = d, a
@@ -423,18 +1127,97 @@ func withScaledObjectOwnersRemoved(scaledObj *kedav1alpha1.ScaledObject) {
 	scaledObj.OwnerReferences = nil
 }
 
+func withScaledObjectName(name string) kedaOption {
+	return func(scaledObj *kedav1alpha1.ScaledObject) {
+		scaledObj.Name = name
+	}
+}
+
+func withScaledObjectHPAName(name string) kedaOption {
+	return func(scaledObj *kedav1alpha1.ScaledObject) {
+		scaledObj.Status.HpaName = name
+	}
+}
+
+func withScaledObjectConditions(conds ...kedav1alpha1.Condition) kedaOption {
+	return func(scaledObj *kedav1alpha1.ScaledObject) {
+		scaledObj.Status.Conditions = conds
+	}
+}
+
+func withScaledObjectHealth(metricName string, status kedav1alpha1.HealthStatusType, failures int32) kedaOption {
+	return func(scaledObj *kedav1alpha1.ScaledObject) {
+		if scaledObj.Status.Health == nil {
+			scaledObj.Status.Health = make(map[string]kedav1alpha1.HealthStatus, 1)
+		}
+		scaledObj.Status.Health[metricName] = kedav1alpha1.HealthStatus{
+			NumberOfFailures: ptr.Int32(failures),
+			Status:           status,
+		}
+	}
+}
+
 func scaledObject(pa *autoscalingv1alpha1.PodAutoscaler, options ...kedaOption) *kedav1alpha1.ScaledObject {
 	k, _ := kedaresources.DesiredScaledObject(hpaconfig.ToContext(context.Background(), &hpaconfig.Config{
 		Autoscaler:     defaultConfig().Autoscaler,
-		AutoscalerKeda: defaultConfig().AutoscalerKeda}), pa)
+		AutoscalerKeda: defaultConfig().AutoscalerKeda}), pa, nil)
 	for _, o := range options {
 		o(k)
 	}
 	return k
 }
 
+func withPrometheusMetric(pa *autoscalingv1alpha1.PodAutoscaler) {
+	helpers.WithAnnotations(map[string]string{
+		autoscaling.MetricAnnotationKey:                      "http_requests_total",
+		autoscaling.TargetAnnotationKey:                      "5",
+		kedaresources.KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m]))",
+		// The query observes the requests buffered by the activator.
+		kedaresources.KedaAutoscaleAnnotationActivateFromZero: "true",
+	})(pa)
+}
+
+// withConcurrencyMetric scales on the concurrency of the revision, targeting 100
+// requests per pod.
+func withConcurrencyMetric(pa *autoscalingv1alpha1.PodAutoscaler) {
+	helpers.WithAnnotations(map[string]string{
+		autoscaling.MetricAnnotationKey:                      autoscaling.Concurrency,
+		autoscaling.TargetAnnotationKey:                      "100",
+		kedaresources.KedaAutoscaleAnnotationPrometheusQuery: "sum(revision_queue_depth{})",
+	})(pa)
+}
+
+func withPrometheusQuery(query string) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		pa.Annotations[kedaresources.KedaAutoscaleAnnotationPrometheusQuery] = query
+	}
+}
+
+func withMinScale(minScale int) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		helpers.WithAnnotations(map[string]string{
+			autoscaling.MinScaleAnnotationKey: strconv.Itoa(minScale),
+		})(pa)
+	}
+}
+
+func withTBC(tbc int) PodAutoscalerOption {
+	return func(pa *autoscalingv1alpha1.PodAutoscaler) {
+		helpers.WithAnnotations(map[string]string{
+			autoscaling.TargetBurstCapacityKey: strconv.Itoa(tbc),
+		})(pa)
+	}
+}
+
 type deploymentOption func(*appsv1.Deployment)
 
+func withDeployReplicas(want, ready int32) deploymentOption {
+	return func(d *appsv1.Deployment) {
+		d.Spec.Replicas = ptr.Int32(want)
+		d.Status.ReadyReplicas = ready
+	}
+}
+
 func deploy(namespace, name string, opts ...deploymentOption) *appsv1.Deployment {
 	s := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -467,6 +1250,18 @@ func defaultConfig() *hpaconfig.Config {
 	}
 }
 
+// testConfigKey is the key of the OtherTestData of a row holding the config to reconcile
+// the row with, instead of the defaultConfig.
+const testConfigKey = "config"
+
+// configWithFallback returns the default config, with a fallback to 2 replicas after
+// 3 failures.
+func configWithFallback() *hpaconfig.Config {
+	config := defaultConfig()
+	config.AutoscalerKeda.Fallback = &kedav1alpha1.Fallback{FailureThreshold: 3, Replicas: 2}
+	return config
+}
+
 type testConfigStore struct {
 	config *hpaconfig.Config
 }
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/apis"
+)
+
+// kedaScalersVersion is the KEDA version kedaScalers are taken from.
+const kedaScalersVersion = "v2.16.1"
+
+// kedaScalers are the trigger types of the scalers of KEDA v2.16.1, to be updated along
+// with the KEDA dependency.
+var kedaScalers = sets.New(
+	"activemq", "apache-kafka", "arangodb", "artemis-queue", "aws-cloudwatch", "aws-dynamodb",
+	"aws-dynamodb-streams", "aws-kinesis-stream", "aws-sqs-queue", "azure-app-insights", "azure-blob",
+	"azure-data-explorer", "azure-eventhub", "azure-log-analytics", "azure-monitor", "azure-pipelines",
+	"azure-queue", "azure-servicebus", "beanstalkd", "cassandra", "couchdb", "cpu", "cron", "datadog",
+	"dynatrace", "elasticsearch", "etcd", "external", "external-push", "gcp-cloudtasks", "gcp-pubsub",
+	"gcp-stackdriver", "gcp-storage", "github-runner", "graphite", "huawei-cloudeye", "ibmmq", "influxdb",
+	"kafka", "kubernetes-workload", "liiklus", "loki", "memory", "metrics-api", "mongodb", "mssql", "mysql",
+	"nats-jetstream", "new-relic", "nsq", "openstack-metric", "openstack-swift", "postgresql", "predictkube",
+	"prometheus", "pulsar", "rabbitmq", "redis", "redis-cluster", "redis-cluster-streams", "redis-sentinel",
+	"redis-sentinel-streams", "redis-streams", "selenium-grid", "solace-event-queue", "solr", "splunk", "stan",
+)
+
+// annotationTrigger is the trigger being assembled from its annotations, along with the
+// keys of its type and auth kind annotations for the errors.
+type annotationTrigger struct {
+	v1alpha1.ScaleTriggers
+	typeKey     string
+	authKindKey string
+}
+
+// getAnnotationTriggers assembles the triggers specified by the annotations of the
+// `trigger.<name>.<field>` family, sorted by name. The fields are `type`, `metric-type`,
+// `auth-name`, `auth-kind` and `metadata.<key>`.
+func getAnnotationTriggers(annotations map[string]string) ([]v1alpha1.ScaleTriggers, *apis.FieldError) {
+	var errs *apis.FieldError
+	triggers := map[string]*annotationTrigger{}
+	for k, v := range annotations {
+		rest, ok := strings.CutPrefix(k, KedaAutoscaleAnnotationTriggerPrefix)
+		if !ok {
+			continue
+		}
+		name, field, _ := strings.Cut(rest, ".")
+		if name == "" || field == "" {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField, "must be "+KedaAutoscaleAnnotationTriggerPrefix+"<name>.<field>"))
+			continue
+		}
+		t, ok := triggers[name]
+		if !ok {
+			t = &annotationTrigger{ScaleTriggers: v1alpha1.ScaleTriggers{Name: name}}
+			triggers[name] = t
+		}
+		switch field {
+		case "type":
+			if !kedaScalers.Has(v) {
+				errs = errs.Also(apis.ErrInvalidValue(v, k, "unknown KEDA scaler"))
+			}
+			t.Type = v
+			t.typeKey = k
+		case "metric-type":
+			mt := autoscalingv2.MetricTargetType(v)
+			if mt != autoscalingv2.AverageValueMetricType && mt != autoscalingv2.ValueMetricType && mt != autoscalingv2.UtilizationMetricType {
+				errs = errs.Also(apis.ErrInvalidValue(v, k, "must be AverageValue, Value or Utilization"))
+			}
+			t.MetricType = mt
+		case "auth-name":
+			if t.AuthenticationRef == nil {
+				t.AuthenticationRef = &v1alpha1.AuthenticationRef{}
+			}
+			t.AuthenticationRef.Name = v
+		case "auth-kind":
+			if v != "TriggerAuthentication" && v != "ClusterTriggerAuthentication" {
+				errs = errs.Also(apis.ErrInvalidValue(v, k, "must be TriggerAuthentication or ClusterTriggerAuthentication"))
+			}
+			if t.AuthenticationRef == nil {
+				t.AuthenticationRef = &v1alpha1.AuthenticationRef{}
+			}
+			t.AuthenticationRef.Kind = v
+			t.authKindKey = k
+		default:
+			key, ok := strings.CutPrefix(field, "metadata.")
+			if !ok || key == "" {
+				errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField, "unknown trigger field "+field))
+				continue
+			}
+			if t.Metadata == nil {
+				t.Metadata = map[string]string{}
+			}
+			t.Metadata[key] = v
+		}
+	}
+
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]v1alpha1.ScaleTriggers, 0, len(names))
+	for _, name := range names {
+		t := triggers[name]
+		if t.typeKey == "" {
+			errs = errs.Also(apis.ErrMissingField(KedaAutoscaleAnnotationTriggerPrefix + name + ".type"))
+		}
+		if t.authKindKey != "" && t.AuthenticationRef.Name == "" {
+			errs = errs.Also(apis.ErrMissingField(KedaAutoscaleAnnotationTriggerPrefix + name + ".auth-name"))
+		}
+		if t.MetricType == autoscalingv2.UtilizationMetricType && t.Type != "cpu" && t.Type != "memory" {
+			errs = errs.Also(apis.ErrGeneric("the Utilization metric type is only supported by the cpu and memory scalers",
+				KedaAutoscaleAnnotationTriggerPrefix+name+".metric-type"))
+		}
+		result = append(result, t.ScaleTriggers)
+	}
+	return result, errs
+}
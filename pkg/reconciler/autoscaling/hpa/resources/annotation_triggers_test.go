@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+// TestKedaScalers fails when the KEDA dependency is updated, as a reminder to update
+// the scalers of the annotation triggers.
+func TestKedaScalers(t *testing.T) {
+	goMod, err := os.ReadFile("../../../../../go.mod")
+	if err != nil {
+		t.Fatal("Failed to read go.mod:", err)
+	}
+	m := regexp.MustCompile(`(?m)^\s*github\.com/kedacore/keda/v2 (\S+)`).FindSubmatch(goMod)
+	if m == nil {
+		t.Fatal("KEDA is not a dependency in go.mod")
+	}
+	if got := string(m[1]); got != kedaScalersVersion {
+		t.Errorf("KEDA version = %s, want %s: update kedaScalers and kedaScalersVersion", got, kedaScalersVersion)
+	}
+	if got, want := kedaScalers.Len(), 68; got != want {
+		t.Errorf("len(kedaScalers) = %d, want %d", got, want)
+	}
+}
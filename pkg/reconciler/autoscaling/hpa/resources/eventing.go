@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+const (
+	// defaultEventingQueueLength is the length of the RabbitMQ queue per replica of the
+	// triggers derived from RabbitmqSources.
+	defaultEventingQueueLength = "10"
+
+	// kafkaGroupIDStatusAnnotation is the status annotation the Kafka broker reports the
+	// consumer group of a Trigger in.
+	kafkaGroupIDStatusAnnotation = "group.id"
+
+	// eventingTriggerSkippedReason is the reason of the event emitted on the PA when a
+	// derived trigger clashes with another trigger of the revision.
+	eventingTriggerSkippedReason = "EventingTriggerSkipped"
+)
+
+var (
+	kafkaSourceResource    = schema.GroupVersionResource{Group: "sources.knative.dev", Version: "v1beta1", Resource: "kafkasources"}
+	rabbitmqSourceResource = schema.GroupVersionResource{Group: "sources.knative.dev", Version: "v1alpha1", Resource: "rabbitmqsources"}
+	brokerTriggerResource  = schema.GroupVersionResource{Group: "eventing.knative.dev", Version: "v1", Resource: "triggers"}
+)
+
+// EventingSourceResources are the Knative Eventing resources the triggers of the revisions
+// opting in with the eventing-triggers annotation are derived from.
+var EventingSourceResources = []schema.GroupVersionResource{
+	kafkaSourceResource,
+	rabbitmqSourceResource,
+	brokerTriggerResource,
+}
+
+// EventingTriggersEnabled returns whether the triggers of the PA are derived from the
+// eventing sources targeting its Service.
+func EventingTriggersEnabled(pa *autoscalingv1alpha1.PodAutoscaler) bool {
+	enabled, _ := strconv.ParseBool(pa.Annotations[KedaAutoscaleAnnotationEventingTriggers])
+	return enabled
+}
+
+// EventingTriggers returns the triggers derived from the eventing sources whose sink is the
+// Service of the PA, if its revision is routed, sorted by the kind and name of the sources so that the ScaledObject
+// does not depend on the informer order. The Kafka brokers of the Triggers are the given
+// ones, the Triggers are skipped if there are none.
+func EventingTriggers(pa *autoscalingv1alpha1.PodAutoscaler, sources []*unstructured.Unstructured, bootstrapServers string) []v1alpha1.ScaleTriggers {
+	service := pa.Labels[serving.ServiceLabelKey]
+	if service == "" {
+		return nil
+	}
+	// The sources of the revisions without routes do not deliver events to them.
+	if pa.Spec.Reachability != autoscalingv1alpha1.ReachabilityReachable {
+		return nil
+	}
+	sources = append([]*unstructured.Unstructured(nil), sources...)
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].GetKind() != sources[j].GetKind() {
+			return sources[i].GetKind() < sources[j].GetKind()
+		}
+		return sources[i].GetName() < sources[j].GetName()
+	})
+
+	var triggers []v1alpha1.ScaleTriggers
+	for _, source := range sources {
+		if source.GetNamespace() != pa.Namespace {
+			continue
+		}
+		if sink, ok := EventingSinkService(source); !ok || sink != service {
+			continue
+		}
+		trigger := eventingTrigger(source, pa.Annotations, bootstrapServers)
+		if trigger == nil {
+			continue
+		}
+		trigger.Name = strings.ToLower(source.GetKind()) + "-" + strings.ReplaceAll(source.GetName(), ".", "-")
+		triggers = append(triggers, *trigger)
+	}
+	return triggers
+}
+
+// eventingTrigger returns the trigger derived from the source, or nil if no backlog can be
+// observed from it.
+func eventingTrigger(source *unstructured.Unstructured, annotations map[string]string, bootstrapServers string) *v1alpha1.ScaleTriggers {
+	switch source.GroupVersionKind().Group + "/" + source.GetKind() {
+	case kafkaSourceResource.Group + "/KafkaSource":
+		servers, _, _ := unstructured.NestedStringSlice(source.Object, "spec", "bootstrapServers")
+		group, _, _ := unstructured.NestedString(source.Object, "spec", "consumerGroup")
+		if len(servers) == 0 || group == "" {
+			return nil
+		}
+		trigger := &v1alpha1.ScaleTriggers{
+			Type: "kafka",
+			Metadata: map[string]string{
+				"bootstrapServers": strings.Join(servers, ","),
+				"consumerGroup":    group,
+			},
+		}
+		// Without a topic, KEDA observes the lag of all the topics of the consumer group.
+		if topics, _, _ := unstructured.NestedStringSlice(source.Object, "spec", "topics"); len(topics) == 1 {
+			trigger.Metadata["topic"] = topics[0]
+		}
+		return trigger
+	case rabbitmqSourceResource.Group + "/RabbitmqSource":
+		// The connection of the source is not usable by KEDA, so the host of the broker
+		// must be provided by a TriggerAuthentication.
+		authName := annotations[KedaAutoscaleAnnotationEventingRabbitmqAuthName]
+		queue, _, _ := unstructured.NestedString(source.Object, "spec", "rabbitmqResourcesConfig", "queueName")
+		if authName == "" || queue == "" {
+			return nil
+		}
+		return &v1alpha1.ScaleTriggers{
+			Type: "rabbitmq",
+			Metadata: map[string]string{
+				"queueName": queue,
+				"mode":      "QueueLength",
+				"value":     defaultEventingQueueLength,
+			},
+			AuthenticationRef: &v1alpha1.AuthenticationRef{
+				Name: authName,
+				Kind: annotations[KedaAutoscaleAnnotationEventingRabbitmqAuthKind],
+			},
+		}
+	case brokerTriggerResource.Group + "/Trigger":
+		// Only the Kafka broker exposes the consumer group of a Trigger.
+		group, _, _ := unstructured.NestedString(source.Object, "status", "annotations", kafkaGroupIDStatusAnnotation)
+		if group == "" || bootstrapServers == "" {
+			return nil
+		}
+		return &v1alpha1.ScaleTriggers{
+			Type: "kafka",
+			Metadata: map[string]string{
+				"bootstrapServers": bootstrapServers,
+				"consumerGroup":    group,
+			},
+		}
+	}
+	return nil
+}
+
+// validateEventingRabbitmqAuth checks the TriggerAuthentication of the RabbitMQ triggers
+// derived from the RabbitmqSources.
+func validateEventingRabbitmqAuth(annotations map[string]string) error {
+	kind, hasKind := annotations[KedaAutoscaleAnnotationEventingRabbitmqAuthKind]
+	if !hasKind {
+		return nil
+	}
+	if annotations[KedaAutoscaleAnnotationEventingRabbitmqAuthName] == "" {
+		return fmt.Errorf("%s requires %s", KedaAutoscaleAnnotationEventingRabbitmqAuthKind, KedaAutoscaleAnnotationEventingRabbitmqAuthName)
+	}
+	if kind != "TriggerAuthentication" && kind != "ClusterTriggerAuthentication" {
+		return fmt.Errorf("%s must be TriggerAuthentication or ClusterTriggerAuthentication, was: %q", KedaAutoscaleAnnotationEventingRabbitmqAuthKind, kind)
+	}
+	return nil
+}
+
+// EventingSinkService returns the name of the Knative Service of the source namespace the
+// source delivers its events to, or false if it delivers them to another kind of sink.
+func EventingSinkService(source *unstructured.Unstructured) (string, bool) {
+	fields := []string{"spec", "sink", "ref"}
+	if source.GroupVersionKind().Group == brokerTriggerResource.Group {
+		fields = []string{"spec", "subscriber", "ref"}
+	}
+	ref, ok, _ := unstructured.NestedStringMap(source.Object, fields...)
+	if !ok {
+		return "", false
+	}
+	if ns := ref["namespace"]; ns != "" && ns != source.GetNamespace() {
+		return "", false
+	}
+	if ref["kind"] != "Service" || !strings.HasPrefix(ref["apiVersion"], serving.GroupName+"/") || ref["name"] == "" {
+		return "", false
+	}
+	return ref["name"], true
+}
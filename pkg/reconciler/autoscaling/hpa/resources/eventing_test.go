@@ -0,0 +1,195 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	. "knative.dev/serving/pkg/testing" //nolint:all
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+)
+
+func eventingSource(apiVersion, kind, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	source := &unstructured.Unstructured{Object: fields}
+	source.SetAPIVersion(apiVersion)
+	source.SetKind(kind)
+	source.SetNamespace(helpers.TestNamespace)
+	source.SetName(name)
+	return source
+}
+
+func serviceRef(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"name":       name,
+	}
+}
+
+func TestEventingTriggers(t *testing.T) {
+	sources := []*unstructured.Unstructured{
+		eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "orders", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"bootstrapServers": []interface{}{"kafka-0:9092", "kafka-1:9092"},
+				"topics":           []interface{}{"orders"},
+				"consumerGroup":    "orders-consumer",
+				"sink":             map[string]interface{}{"ref": serviceRef("shop")},
+			},
+		}),
+		eventingSource("sources.knative.dev/v1alpha1", "RabbitmqSource", "payments.eu", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"rabbitmqResourcesConfig": map[string]interface{}{"queueName": "payments"},
+				"sink":                    map[string]interface{}{"ref": serviceRef("shop")},
+			},
+		}),
+		eventingSource("eventing.knative.dev/v1", "Trigger", "shipments", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"broker":     "default",
+				"subscriber": map[string]interface{}{"ref": serviceRef("shop")},
+			},
+			"status": map[string]interface{}{
+				"annotations": map[string]interface{}{"group.id": "knative-trigger-test-namespace-shipments"},
+			},
+		}),
+		// The sink of the source is another service.
+		eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "invoices", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"bootstrapServers": []interface{}{"kafka-0:9092"},
+				"consumerGroup":    "invoices-consumer",
+				"sink":             map[string]interface{}{"ref": serviceRef("billing")},
+			},
+		}),
+		// The broker of the trigger does not expose its consumer group.
+		eventingSource("eventing.knative.dev/v1", "Trigger", "audit", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"broker":     "default",
+				"subscriber": map[string]interface{}{"ref": serviceRef("shop")},
+			},
+		}),
+	}
+
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithReachabilityReachable, helpers.WithAnnotations(map[string]string{
+		KedaAutoscaleAnnotationEventingTriggers:         "true",
+		KedaAutoscaleAnnotationEventingRabbitmqAuthName: "rabbitmq-auth",
+	}))
+	pa.Labels = map[string]string{serving.ServiceLabelKey: "shop"}
+
+	got := EventingTriggers(pa, sources, "kafka-broker:9092")
+	want := []v1alpha1.ScaleTriggers{{
+		Name: "kafkasource-orders",
+		Type: "kafka",
+		Metadata: map[string]string{
+			"bootstrapServers": "kafka-0:9092,kafka-1:9092",
+			"consumerGroup":    "orders-consumer",
+			"topic":            "orders",
+		},
+	}, {
+		Name: "rabbitmqsource-payments-eu",
+		Type: "rabbitmq",
+		Metadata: map[string]string{
+			"queueName": "payments",
+			"mode":      "QueueLength",
+			"value":     "10",
+		},
+		AuthenticationRef: &v1alpha1.AuthenticationRef{Name: "rabbitmq-auth"},
+	}, {
+		Name: "trigger-shipments",
+		Type: "kafka",
+		Metadata: map[string]string{
+			"bootstrapServers": "kafka-broker:9092",
+			"consumerGroup":    "knative-trigger-test-namespace-shipments",
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Triggers mismatch: diff(-want,+got):\n%s", diff)
+	}
+
+	// The triggers of the brokers are skipped when the brokers are not known, and the
+	// triggers of the RabbitmqSources without a TriggerAuthentication providing the host.
+	pa.Annotations = map[string]string{KedaAutoscaleAnnotationEventingTriggers: "true"}
+	if got := EventingTriggers(pa, sources[1:], ""); len(got) != 0 {
+		t.Errorf("EventingTriggers() = %v, want none without bootstrap servers and auth", got)
+	}
+
+	// The sources do not deliver events to the revisions without routes.
+	pa.Spec.Reachability = autoscalingv1alpha1.ReachabilityUnreachable
+	if got := EventingTriggers(pa, sources, "kafka-broker:9092"); len(got) != 0 {
+		t.Errorf("EventingTriggers() = %v, want none for an unreachable revision", got)
+	}
+}
+
+func TestEventingSinkService(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      *unstructured.Unstructured
+		wantService string
+		wantOK      bool
+	}{{
+		name: "source",
+		source: eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "orders", map[string]interface{}{
+			"spec": map[string]interface{}{"sink": map[string]interface{}{"ref": serviceRef("shop")}},
+		}),
+		wantService: "shop",
+		wantOK:      true,
+	}, {
+		name: "broker trigger",
+		source: eventingSource("eventing.knative.dev/v1", "Trigger", "shipments", map[string]interface{}{
+			"spec": map[string]interface{}{"subscriber": map[string]interface{}{"ref": serviceRef("shop")}},
+		}),
+		wantService: "shop",
+		wantOK:      true,
+	}, {
+		name: "service of another namespace",
+		source: eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "orders", map[string]interface{}{
+			"spec": map[string]interface{}{"sink": map[string]interface{}{"ref": map[string]interface{}{
+				"apiVersion": "serving.knative.dev/v1",
+				"kind":       "Service",
+				"name":       "shop",
+				"namespace":  "other",
+			}}},
+		}),
+	}, {
+		name: "kubernetes service",
+		source: eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "orders", map[string]interface{}{
+			"spec": map[string]interface{}{"sink": map[string]interface{}{"ref": map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"name":       "shop",
+			}}},
+		}),
+	}, {
+		name: "uri sink",
+		source: eventingSource("sources.knative.dev/v1beta1", "KafkaSource", "orders", map[string]interface{}{
+			"spec": map[string]interface{}{"sink": map[string]interface{}{"uri": "http://shop.example.com"}},
+		}),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, ok := EventingSinkService(tt.source)
+			if service != tt.wantService || ok != tt.wantOK {
+				t.Errorf("EventingSinkService() = %q, %v, want %q, %v", service, ok, tt.wantService, tt.wantOK)
+			}
+		})
+	}
+}
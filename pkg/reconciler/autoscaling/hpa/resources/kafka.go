@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+// kafkaTriggerName is the name of the trigger of the Kafka annotations.
+const kafkaTriggerName = "kafka"
+
+// kafkaAnnotations are the annotations the Kafka trigger is built from.
+var kafkaAnnotations = []string{
+	KedaAutoscaleAnnotationKafkaBootstrapServers,
+	KedaAutoscaleAnnotationKafkaTopic,
+	KedaAutoscaleAnnotationKafkaConsumerGroup,
+	KedaAutoscaleAnnotationKafkaLagThreshold,
+	KedaAutoscaleAnnotationKafkaActivationLagThreshold,
+	KedaAutoscaleAnnotationKafkaAuthName,
+	KedaAutoscaleAnnotationKafkaAuthKind,
+}
+
+// getKafkaTrigger returns the trigger scaling the revision on the lag of its Kafka consumer
+// group, or nil if none of the Kafka annotations is specified. The bootstrap servers default
+// to the given ones, and the consumer group is rendered like the Prometheus query.
+func getKafkaTrigger(pa *autoscalingv1alpha1.PodAutoscaler, defaultBootstrapServers string) (*v1alpha1.ScaleTriggers, error) {
+	specified := false
+	for _, k := range kafkaAnnotations {
+		if _, ok := pa.Annotations[k]; ok {
+			specified = true
+			break
+		}
+	}
+	if !specified {
+		return nil, nil
+	}
+
+	bootstrapServers := defaultBootstrapServers
+	if v, ok := pa.Annotations[KedaAutoscaleAnnotationKafkaBootstrapServers]; ok {
+		bootstrapServers = v
+	}
+	if bootstrapServers == "" {
+		return nil, fmt.Errorf("%s is required as no default bootstrap servers are configured", KedaAutoscaleAnnotationKafkaBootstrapServers)
+	}
+	group := pa.Annotations[KedaAutoscaleAnnotationKafkaConsumerGroup]
+	if group == "" {
+		return nil, fmt.Errorf("%s is required to scale on the Kafka consumer lag", KedaAutoscaleAnnotationKafkaConsumerGroup)
+	}
+	group, err := renderQuery(group, queryValues(pa))
+	if err != nil {
+		return nil, fmt.Errorf("unable to render %s: %w", KedaAutoscaleAnnotationKafkaConsumerGroup, err)
+	}
+
+	trigger := &v1alpha1.ScaleTriggers{
+		Type:       "kafka",
+		Name:       kafkaTriggerName,
+		MetricType: autoscalingv2.AverageValueMetricType,
+		Metadata: map[string]string{
+			"bootstrapServers": bootstrapServers,
+			"consumerGroup":    group,
+		},
+	}
+	if v := pa.Annotations[KedaAutoscaleAnnotationKafkaTopic]; v != "" {
+		trigger.Metadata["topic"] = v
+	}
+	if v, ok := pa.Annotations[KedaAutoscaleAnnotationKafkaLagThreshold]; ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q, must be a positive whole number", KedaAutoscaleAnnotationKafkaLagThreshold, v)
+		}
+		trigger.Metadata["lagThreshold"] = v
+	}
+	if v, ok := pa.Annotations[KedaAutoscaleAnnotationKafkaActivationLagThreshold]; ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s: %q, must be a whole number", KedaAutoscaleAnnotationKafkaActivationLagThreshold, v)
+		}
+		trigger.Metadata["activationLagThreshold"] = v
+	}
+
+	authName, hasAuthName := pa.Annotations[KedaAutoscaleAnnotationKafkaAuthName]
+	authKind, hasAuthKind := pa.Annotations[KedaAutoscaleAnnotationKafkaAuthKind]
+	if hasAuthKind && !hasAuthName {
+		return nil, fmt.Errorf("%s must be specified with %s", KedaAutoscaleAnnotationKafkaAuthName, KedaAutoscaleAnnotationKafkaAuthKind)
+	}
+	if hasAuthKind && authKind != "TriggerAuthentication" && authKind != "ClusterTriggerAuthentication" {
+		return nil, fmt.Errorf("invalid %s: %q, must be TriggerAuthentication or ClusterTriggerAuthentication", KedaAutoscaleAnnotationKafkaAuthKind, authKind)
+	}
+	if hasAuthName {
+		trigger.AuthenticationRef = &v1alpha1.AuthenticationRef{Name: authName, Kind: authKind}
+	}
+	return trigger, nil
+}
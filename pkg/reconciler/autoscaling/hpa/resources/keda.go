@@ -23,17 +23,26 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"regexp"
+	"slices"
+	"strconv"
 	"text/template"
 
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/ptr"
 	"knative.dev/serving/pkg/apis/autoscaling"
 	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
+	aresources "knative.dev/serving/pkg/reconciler/autoscaling/resources"
 
 	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
 	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
@@ -52,47 +61,103 @@ const (
 	KedaAutoscalingAnnotationHPAScaleUpRules       = autoscaling.GroupName + "/hpa-scale-up-rules"
 	KedaAutoscalingAnnotationHPAScaleDownRules     = autoscaling.GroupName + "/hpa-scale-down-rules"
 	KedaAutoscaleAnnotationsScaledObjectOverride   = autoscaling.GroupName + "/scaled-object-override"
+	KedaAutoscaleAnnotationScaledObjectPatch       = autoscaling.GroupName + "/scaled-object-patch"
+
+	// KedaAutoscaleAnnotationActivateFromZero states that the Prometheus triggers of a revision
+	// observe its load while it has no pods, e.g. the requests buffered by the activator.
+	KedaAutoscaleAnnotationActivateFromZero = autoscaling.GroupName + "/activate-from-zero"
+
+	KedaAutoscaleAnnotationPollingInterval               = autoscaling.GroupName + "/polling-interval"
+	KedaAutoscaleAnnotationCooldownPeriod                = autoscaling.GroupName + "/cooldown-period"
+	KedaAutoscaleAnnotationInitialCooldownPeriod         = autoscaling.GroupName + "/initial-cooldown-period"
+	KedaAutoscaleAnnotationIdleReplicaCount              = autoscaling.GroupName + "/idle-replica-count"
+	KedaAutoscaleAnnotationFallbackFailureThreshold      = autoscaling.GroupName + "/fallback-failure-threshold"
+	KedaAutoscaleAnnotationFallbackReplicas              = autoscaling.GroupName + "/fallback-replicas"
+	KedaAutoscaleAnnotationRestoreToOriginalReplicaCount = autoscaling.GroupName + "/restore-to-original-replica-count"
+
+	KedaAutoscaleAnnotationPrometheusQueryMetric      = autoscaling.GroupName + "/prometheus-query-metric"
+	KedaAutoscaleAnnotationPrometheusQueryLabels      = autoscaling.GroupName + "/prometheus-query-labels"
+	KedaAutoscaleAnnotationPrometheusQueryAggregation = autoscaling.GroupName + "/prometheus-query-aggregation"
+	KedaAutoscaleAnnotationPrometheusQueryRateWindow  = autoscaling.GroupName + "/prometheus-query-rate-window"
+	KedaAutoscaleAnnotationPrometheusQueryQuantile    = autoscaling.GroupName + "/prometheus-query-quantile"
+
+	// KedaAutoscaleAnnotationTriggerPrefix is the prefix of the annotations specifying a trigger
+	// of any KEDA scaler, followed by the name of the trigger and the field, e.g. `kafka.type`.
+	KedaAutoscaleAnnotationTriggerPrefix = autoscaling.GroupName + "/trigger."
+
+	// KedaAutoscaleAnnotationEventingTriggers opts a revision in to triggers derived from the
+	// Knative Eventing sources whose sink is its Service.
+	KedaAutoscaleAnnotationEventingTriggers = autoscaling.GroupName + "/eventing-triggers"
+	// KedaAutoscaleAnnotationEventingRabbitmqAuthName and KedaAutoscaleAnnotationEventingRabbitmqAuthKind
+	// reference the TriggerAuthentication providing the host of the RabbitMQ triggers derived from
+	// the RabbitmqSources, which are only derived if it is set.
+	KedaAutoscaleAnnotationEventingRabbitmqAuthName = autoscaling.GroupName + "/eventing-rabbitmq-auth-name"
+	KedaAutoscaleAnnotationEventingRabbitmqAuthKind = autoscaling.GroupName + "/eventing-rabbitmq-auth-kind"
+
+	KedaAutoscaleAnnotationTriggerTemplate       = autoscaling.GroupName + "/trigger-template"
+	KedaAutoscaleAnnotationTriggerTemplateParams = autoscaling.GroupName + "/trigger-template-params"
+
+	KedaAutoscaleAnnotationScheduleStart           = autoscaling.GroupName + "/schedule-start"
+	KedaAutoscaleAnnotationScheduleEnd             = autoscaling.GroupName + "/schedule-end"
+	KedaAutoscaleAnnotationScheduleDesiredReplicas = autoscaling.GroupName + "/schedule-desired-replicas"
+	KedaAutoscaleAnnotationScheduleTimezone        = autoscaling.GroupName + "/schedule-timezone"
+	KedaAutoscaleAnnotationSchedules               = autoscaling.GroupName + "/schedules"
+
+	KedaAutoscaleAnnotationKafkaBootstrapServers       = autoscaling.GroupName + "/kafka-bootstrap-servers"
+	KedaAutoscaleAnnotationKafkaTopic                  = autoscaling.GroupName + "/kafka-topic"
+	KedaAutoscaleAnnotationKafkaConsumerGroup          = autoscaling.GroupName + "/kafka-consumer-group"
+	KedaAutoscaleAnnotationKafkaLagThreshold           = autoscaling.GroupName + "/kafka-lag-threshold"
+	KedaAutoscaleAnnotationKafkaActivationLagThreshold = autoscaling.GroupName + "/kafka-activation-lag-threshold"
+	KedaAutoscaleAnnotationKafkaAuthName               = autoscaling.GroupName + "/kafka-auth-name"
+	KedaAutoscaleAnnotationKafkaAuthKind               = autoscaling.GroupName + "/kafka-auth-kind"
+
+	KedaAutoscaleAnnotationTargetLatency         = autoscaling.GroupName + "/target-latency"
+	KedaAutoscaleAnnotationTargetLatencyQuantile = autoscaling.GroupName + "/target-latency-quantile"
+	KedaAutoscaleAnnotationTargetLatencyMetric   = autoscaling.GroupName + "/target-latency-metric"
 
 	defaultCPUTarget = 70
+
+	defaultCPUTriggerName    = "default-trigger-cpu"
+	defaultMemoryTriggerName = "default-trigger-memory"
+	defaultCustomTriggerName = "default-trigger-custom"
 )
 
-// DesiredScaledObject creates an ScaledObject KEDA resource from a PA resource.
-func DesiredScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler) (*v1alpha1.ScaledObject, error) {
+// DesiredScaledObject creates an ScaledObject KEDA resource from a PA resource, with the
+// triggers derived from the eventing sources of the revision if any.
+func DesiredScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, eventingTriggers []v1alpha1.ScaleTriggers) (*v1alpha1.ScaledObject, error) {
 
 	config := hpaconfig.FromContext(ctx).Autoscaler
 	autoscalerkedaconfig := hpaconfig.FromContext(ctx).AutoscalerKeda
+	if autoscalerkedaconfig == nil {
+		// The reconciler also treats a missing autoscaler keda config as the defaults.
+		autoscalerkedaconfig, _ = hpaconfig.NewConfigFromMap(nil)
+	}
 
 	minScale, maxScale := pa.ScaleBounds(config)
 	if maxScale == 0 {
 		maxScale = math.MaxInt32 // default to no limit
 	}
 
-	var sO v1alpha1.ScaledObject
-	if v, ok := pa.Annotations[KedaAutoscaleAnnotationsScaledObjectOverride]; ok {
-		if err := json.Unmarshal([]byte(v), &sO); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal scaled object override: %w", err)
-		}
-		setScaledObjectDefaults(&sO, maxScale, pa)
-		return &sO, nil
+	if override, err := getScaledObjectOverride(pa.Annotations); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal scaled object override: %w", err)
+	} else if override != nil {
+		setScaledObjectDefaults(override, maxScale, pa)
+		return override, nil
 	}
 
-	sO = v1alpha1.ScaledObject{}
+	sO := v1alpha1.ScaledObject{}
 	setScaledObjectDefaults(&sO, maxScale, pa)
 
-	if v, ok := pa.Annotations[KedaAutoscaleAnnotationScalingModifiers]; ok {
-		scalingModifiers := v1alpha1.ScalingModifiers{}
-		if err := json.Unmarshal([]byte(v), &scalingModifiers); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal scaling modifiers: %w", err)
-		}
-		sO.Spec.Advanced.ScalingModifiers = scalingModifiers
-		log.Printf("scaling modifiers: %v\n", scalingModifiers)
-	}
-
-	if minScale > 0 {
-		sO.Spec.MinReplicaCount = ptr.Int32(minScale)
+	if scalingModifiers, err := getScalingModifiers(pa.Annotations); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal scaling modifiers: %w", err)
+	} else if scalingModifiers != nil {
+		sO.Spec.Advanced.ScalingModifiers = *scalingModifiers
+		log.Printf("scaling modifiers: %v\n", *scalingModifiers)
 	}
 
-	if target, ok := resolveTarget(pa); ok {
+	// activatorQuery is the query of the default trigger if it measures a built-in metric.
+	var activatorQuery string
+	if target, ok := resolveTarget(pa, config); ok {
 		mt, err := getMetricType(pa.Annotations, pa.Metric())
 		if err != nil {
 			return nil, err
@@ -101,57 +166,54 @@ func DesiredScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutosca
 		case autoscaling.CPU:
 			sO.Spec.Triggers = []v1alpha1.ScaleTriggers{
 				{
-					Name:       "default-trigger-cpu",
+					Name:       defaultCPUTriggerName,
 					Type:       "cpu",
 					MetricType: *mt,
 					Metadata:   map[string]string{"value": fmt.Sprint(int32(math.Ceil(target)))},
 				},
 			}
-			if minScale <= 0 {
-				sO.Spec.MinReplicaCount = ptr.Int32(1)
-			}
 		case autoscaling.Memory:
 			memory := resource.NewQuantity(int64(target)*1024*1024, resource.BinarySI)
 			sO.Spec.Triggers = []v1alpha1.ScaleTriggers{
 				{
-					Name:       "default-trigger-memory",
+					Name:       defaultMemoryTriggerName,
 					Type:       "memory",
 					MetricType: *mt,
 					Metadata:   map[string]string{"value": memory.String()},
 				},
 			}
-			if minScale <= 0 {
-				sO.Spec.MinReplicaCount = ptr.Int32(1)
-			}
 		default:
-			targetQuantity := resource.NewQuantity(int64(target), resource.DecimalSI)
+			threshold := resource.NewQuantity(int64(target), resource.DecimalSI).String()
 			var query, address string
+			var builtin bool
 			if query, ok = pa.Annotations[KedaAutoscaleAnnotationPrometheusQuery]; !ok {
-				return nil, fmt.Errorf("query is missing for custom metric: %w", err)
+				if query, ok, err = buildQuery(pa.Annotations); err != nil {
+					return nil, err
+				} else if !ok {
+					if query, ok = builtinQuery(pa, config); !ok {
+						return nil, fmt.Errorf("query is missing for custom metric: %w", err)
+					}
+					builtin = true
+				}
+			}
+			if isBuiltinMetric(pa.Metric()) {
+				// The target of built-in metrics is scaled by the target utilization,
+				// so it is not necessarily a whole number.
+				threshold = strconv.FormatFloat(math.Round(target*1000)/1000, 'f', -1, 64)
 			}
 
-			tmpl, err := template.New("query").Parse(query)
+			query, err := renderQuery(query, queryValues(pa))
 			if err != nil {
-				return nil, fmt.Errorf("template initialization failed: %w", err)
-			}
-			values := map[string]string{
-				"revisionName": pa.Name,
+				return nil, err
 			}
-			var output bytes.Buffer
-			if err := tmpl.Execute(&output, values); err != nil {
-				return nil, fmt.Errorf("template execution failed: %w", err)
+			if builtin {
+				activatorQuery = query
 			}
-			query = output.String()
 
-			if v, ok := pa.Annotations[KedaAutoscaleAnnotationPrometheusAddress]; ok {
-				if err := helpers.ParseServerAddress(v); err != nil {
-					return nil, fmt.Errorf("invalid prometheus address: %w", err)
-				}
-				address = v
-			} else {
-				address = autoscalerkedaconfig.PrometheusAddress
+			if address, err = prometheusAddress(pa.Annotations, autoscalerkedaconfig.PrometheusAddress); err != nil {
+				return nil, err
 			}
-			defaultTrigger, err := getDefaultPrometheusTrigger(pa.Annotations, address, query, targetQuantity.String(), pa.Namespace, *mt)
+			defaultTrigger, err := getDefaultPrometheusTrigger(pa.Annotations, autoscalerkedaconfig, address, query, threshold, pa.Namespace, *mt)
 			if err != nil {
 				return nil, err
 			}
@@ -163,13 +225,130 @@ func DesiredScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutosca
 	if err != nil {
 		return nil, err
 	}
+	if len(extraPrometheusTriggers) > 0 {
+		address, err := prometheusAddress(pa.Annotations, autoscalerkedaconfig.PrometheusAddress)
+		if err != nil {
+			return nil, err
+		}
+		if err := renderExtraPrometheusTriggers(extraPrometheusTriggers, queryValues(pa), address); err != nil {
+			return nil, err
+		}
+	}
 
 	sO.Spec.Triggers = append(sO.Spec.Triggers, extraPrometheusTriggers...)
 
+	if _, ok := pa.Annotations[KedaAutoscaleAnnotationTriggerTemplate]; ok {
+		address, err := prometheusAddress(pa.Annotations, autoscalerkedaconfig.PrometheusAddress)
+		if err != nil {
+			return nil, err
+		}
+		templateTrigger, err := getTemplateTrigger(pa, autoscalerkedaconfig.TriggerTemplates, address)
+		if err != nil {
+			return nil, err
+		}
+		sO.Spec.Triggers = append(sO.Spec.Triggers, *templateTrigger)
+	}
+
+	annotationTriggers, fieldErr := getAnnotationTriggers(pa.Annotations)
+	if fieldErr != nil {
+		return nil, fieldErr
+	}
+	if len(annotationTriggers) > 0 {
+		address, err := prometheusAddress(pa.Annotations, autoscalerkedaconfig.PrometheusAddress)
+		if err != nil {
+			return nil, err
+		}
+		if err := renderExtraPrometheusTriggers(annotationTriggers, queryValues(pa), address); err != nil {
+			return nil, err
+		}
+		sO.Spec.Triggers = append(sO.Spec.Triggers, annotationTriggers...)
+	}
+
+	kafkaTrigger, err := getKafkaTrigger(pa, autoscalerkedaconfig.KafkaBootstrapServers)
+	if err != nil {
+		return nil, err
+	}
+	if kafkaTrigger != nil {
+		sO.Spec.Triggers = append(sO.Spec.Triggers, *kafkaTrigger)
+	}
+
+	scheduleTriggers, err := getScheduleTriggers(pa.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	sO.Spec.Triggers = append(sO.Spec.Triggers, scheduleTriggers...)
+
+	// An annotation trigger replaces the derived trigger of the same name. The derived
+	// triggers clashing with the other triggers of the revision are skipped rather than
+	// failing the revision on a change of its sources.
+	for _, t := range eventingTriggers {
+		if slices.ContainsFunc(annotationTriggers, func(a v1alpha1.ScaleTriggers) bool { return a.Name == t.Name }) {
+			continue
+		}
+		if slices.ContainsFunc(sO.Spec.Triggers, func(a v1alpha1.ScaleTriggers) bool { return a.Name == t.Name }) {
+			if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+				recorder.Eventf(pa, corev1.EventTypeWarning, eventingTriggerSkippedReason,
+					"The trigger %q derived from the eventing sources is skipped, the revision already has a trigger of that name", t.Name)
+			}
+			continue
+		}
+		sO.Spec.Triggers = append(sO.Spec.Triggers, *t.DeepCopy())
+	}
+
+	if _, ok := pa.Annotations[KedaAutoscaleAnnotationTargetLatency]; ok {
+		address, err := prometheusAddress(pa.Annotations, autoscalerkedaconfig.PrometheusAddress)
+		if err != nil {
+			return nil, err
+		}
+		latencyTrigger, scalingModifiers, err := getLatencyTrigger(pa, address)
+		if err != nil {
+			return nil, err
+		}
+		// The scaling modifiers replace all the triggers but the cpu and memory ones,
+		// so the other triggers would be silently ignored.
+		for _, t := range sO.Spec.Triggers {
+			if t.Type != "cpu" && t.Type != "memory" {
+				return nil, fmt.Errorf("%s cannot be combined with trigger %q, use %s instead", KedaAutoscaleAnnotationTargetLatency, t.Name, KedaAutoscaleAnnotationScalingModifiers)
+			}
+		}
+		sO.Spec.Triggers = append(sO.Spec.Triggers, *latencyTrigger)
+		sO.Spec.Advanced.ScalingModifiers = *scalingModifiers
+	}
+
 	if len(sO.Spec.Triggers) == 0 {
 		return nil, fmt.Errorf("no triggers were specified, make sure a metric target is specified or extra triggers are added")
 	}
 
+	// KEDA rejects ScaledObjects whose triggers share a name.
+	if name, ok := duplicateTriggerName(sO.Spec.Triggers); ok {
+		return nil, fmt.Errorf("trigger %q is defined more than once", name)
+	}
+
+	if err := validatePrometheusTriggers(sO.Spec.Triggers); err != nil {
+		return nil, err
+	}
+
+	// minReplicas returns the min replicas of the revision with the given triggers, so that
+	// they can be derived again from the triggers of the scaled-object-patch annotation.
+	minReplicas := func(triggers []v1alpha1.ScaleTriggers) int32 {
+		if minScale > 0 {
+			return minScale
+		}
+		// The built-in queries include the requests buffered by the activator, so they
+		// observe the revision while it has no pods.
+		if config.EnableScaleToZero && (hasQuery(triggers, activatorQuery) || canActivateFromZero(pa.Annotations, triggers)) {
+			return 0
+		}
+		return 1
+	}
+	sO.Spec.MinReplicaCount = ptr.Int32(minReplicas(sO.Spec.Triggers))
+
+	setConfigDefaults(&sO, autoscalerkedaconfig)
+
+	if err := setSpecAnnotations(&sO.Spec, pa.Annotations); err != nil {
+		return nil, err
+	}
+
 	if window, hasWindow := pa.Window(); hasWindow {
 		windowSeconds := int32(window.Seconds())
 		sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
@@ -182,32 +361,33 @@ func DesiredScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutosca
 		}
 	}
 
-	if v, ok := pa.Annotations[KedaAutoscalingAnnotationHPAScaleUpRules]; ok {
-		var scaleUpRules autoscalingv2.HPAScalingRules
-		if err := json.Unmarshal([]byte(v), &scaleUpRules); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal scale up rules: %w", err)
-		}
+	if scaleUpRules, err := getScalingRules(pa.Annotations, KedaAutoscalingAnnotationHPAScaleUpRules); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal scale up rules: %w", err)
+	} else if scaleUpRules != nil {
 		if sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior == nil {
 			sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
 		}
-		sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior.ScaleUp = &scaleUpRules
+		sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior.ScaleUp = scaleUpRules
 	}
 
-	if v, ok := pa.Annotations[KedaAutoscalingAnnotationHPAScaleDownRules]; ok {
-		var scaleDownRules autoscalingv2.HPAScalingRules
-		if err := json.Unmarshal([]byte(v), &scaleDownRules); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal scale down rules: %w", err)
-		}
+	if scaleDownRules, err := getScalingRules(pa.Annotations, KedaAutoscalingAnnotationHPAScaleDownRules); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal scale down rules: %w", err)
+	} else if scaleDownRules != nil {
 		if sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior == nil {
 			sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
 		}
-		sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior.ScaleDown = &scaleDownRules
+		sO.Spec.Advanced.HorizontalPodAutoscalerConfig.Behavior.ScaleDown = scaleDownRules
 	}
 
-	return &sO, nil
+	return patchScaledObject(&sO, pa.Annotations, minReplicas)
 }
 
-func resolveTarget(pa *autoscalingv1alpha1.PodAutoscaler) (float64, bool) {
+func resolveTarget(pa *autoscalingv1alpha1.PodAutoscaler, config *autoscalerconfig.Config) (float64, bool) {
+	// Built-in metrics are resolved like the KPA does, honoring the target utilization.
+	if isBuiltinMetric(pa.Metric()) {
+		target, _ := aresources.ResolveMetricTarget(pa, config)
+		return target, true
+	}
 	if target, ok := pa.Target(); ok {
 		return target, true
 	}
@@ -219,13 +399,125 @@ func resolveTarget(pa *autoscalingv1alpha1.PodAutoscaler) (float64, bool) {
 	return 0, false
 }
 
-func getDefaultPrometheusTrigger(annotations map[string]string, address string, query string, threshold string, ns string, targetType autoscalingv2.MetricTargetType) (*v1alpha1.ScaleTriggers, error) {
+// isBuiltinMetric returns true for the KPA metrics the extension generates a query for.
+func isBuiltinMetric(metric string) bool {
+	return metric == autoscaling.RPS || metric == autoscaling.Concurrency
+}
+
+// builtinQuery returns the query template measuring the built-in metric of the revision,
+// or false if the metric is not built-in. The requests sent directly to the queue-proxies
+// are added to the requests buffered or proxied by the activator, so that the revision
+// is observed while it has no pods. The requests proxied by the activator are reported
+// by both and are only counted once. Rates are computed over the stable window of the
+// revision.
+func builtinQuery(pa *autoscalingv1alpha1.PodAutoscaler, config *autoscalerconfig.Config) (string, bool) {
+	const (
+		queueSelector     = `{destination_namespace="{{ .namespace }}", destination_revision="{{ .revisionName }}"}`
+		activatorSelector = `{namespace_name="{{ .namespace }}", revision_name="{{ .revisionName }}"}`
+	)
+	switch pa.Metric() {
+	case autoscaling.RPS:
+		window := config.StableWindow
+		if w, ok := pa.Window(); ok {
+			window = w
+		}
+		rangeSelector := fmt.Sprintf("[%ds]", int(window.Seconds()))
+		return sumOrZero("avg_over_time(queue_requests_per_second"+queueSelector+rangeSelector+")") +
+			" - " + sumOrZero("avg_over_time(queue_proxied_operations_per_second"+queueSelector+rangeSelector+")") +
+			" + " + sumOrZero("rate(activator_request_count"+activatorSelector+rangeSelector+")"), true
+	case autoscaling.Concurrency:
+		return sumOrZero("queue_average_concurrent_requests"+queueSelector) +
+			" - " + sumOrZero("queue_average_proxied_concurrent_requests"+queueSelector) +
+			" + " + sumOrZero("activator_request_concurrency"+activatorSelector), true
+	}
+	return "", false
+}
+
+// sumOrZero sums the samples of the expression, or returns 0 if there are none, as the
+// queue-proxies report no series while the revision has no pods.
+func sumOrZero(expr string) string {
+	return "(sum(" + expr + ") or vector(0))"
+}
+
+// duplicateTriggerName returns the first name shared by several triggers, if any.
+func duplicateTriggerName(triggers []v1alpha1.ScaleTriggers) (string, bool) {
+	names := sets.New[string]()
+	for _, t := range triggers {
+		if t.Name == "" {
+			continue
+		}
+		if names.Has(t.Name) {
+			return t.Name, true
+		}
+		names.Insert(t.Name)
+	}
+	return "", false
+}
+
+// setConfigDefaults sets the ScaledObject fields defaulted in the config-autoscaler-keda
+// ConfigMap. The fallback is only set if KEDA supports it for the triggers.
+func setConfigDefaults(sO *v1alpha1.ScaledObject, config *hpaconfig.AutoscalerKedaConfig) {
+	if config == nil {
+		return
+	}
+	if config.PollingInterval != nil {
+		sO.Spec.PollingInterval = ptr.Int32(*config.PollingInterval)
+	}
+	if config.CooldownPeriod != nil {
+		sO.Spec.CooldownPeriod = ptr.Int32(*config.CooldownPeriod)
+	}
+	if config.InitialCooldownPeriod != nil {
+		sO.Spec.InitialCooldownPeriod = *config.InitialCooldownPeriod
+	}
+	if config.Fallback != nil {
+		sO.Spec.Fallback = config.Fallback.DeepCopy()
+		if err := v1alpha1.CheckFallbackValid(sO); err != nil {
+			sO.Spec.Fallback = nil
+		}
+	}
+}
+
+// podMetricTriggerTypes are the trigger types that usually measure the pods of the
+// revision, and so report no activity while it is scaled to zero.
+var podMetricTriggerTypes = sets.New("cpu", "memory", "prometheus")
+
+// cronTriggerType is the type of the schedule triggers. They only activate the revision
+// during their windows, so they do not decide whether it can scale to zero: outside of
+// the windows nothing would activate it again.
+const cronTriggerType = "cron"
+
+// canActivateFromZero returns true if at least one of the triggers can activate the
+// scale target from zero. KEDA does not support scaling to zero when only cpu or
+// memory triggers are defined as resource metrics require running pods. Prometheus
+// queries are only trusted to observe a revision without pods if it opts in with
+// the activate-from-zero annotation.
+func canActivateFromZero(annotations map[string]string, triggers []v1alpha1.ScaleTriggers) bool {
+	optIn, _ := strconv.ParseBool(annotations[KedaAutoscaleAnnotationActivateFromZero])
+	for _, t := range triggers {
+		if t.Type == cronTriggerType {
+			continue
+		}
+		if !podMetricTriggerTypes.Has(t.Type) || optIn && t.Type == "prometheus" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasQuery returns true if one of the Prometheus triggers runs the query.
+func hasQuery(triggers []v1alpha1.ScaleTriggers, query string) bool {
+	return query != "" && slices.ContainsFunc(triggers, func(t v1alpha1.ScaleTriggers) bool {
+		return t.Type == "prometheus" && t.Metadata["query"] == query
+	})
+}
+
+func getDefaultPrometheusTrigger(annotations map[string]string, defaults *hpaconfig.AutoscalerKedaConfig, address string, query string, threshold string, ns string, targetType autoscalingv2.MetricTargetType) (*v1alpha1.ScaleTriggers, error) {
 	var name string
 
 	if v, ok := annotations[KedaAutoscalerAnnnotationPrometheusName]; ok {
 		name = v
 	} else {
-		name = "default-trigger-custom"
+		name = defaultCustomTriggerName
 	}
 	trigger := v1alpha1.ScaleTriggers{
 		Type:       "prometheus",
@@ -240,6 +532,16 @@ func getDefaultPrometheusTrigger(annotations map[string]string, address string,
 
 	var ref *v1alpha1.AuthenticationRef
 
+	// The default authentication only applies to the revisions not specifying their own.
+	if !hasAnyAnnotation(annotations, prometheusAuthAnnotations) && defaults != nil && defaults.PrometheusAuthName != "" {
+		trigger.AuthenticationRef = &v1alpha1.AuthenticationRef{
+			Name: defaults.PrometheusAuthName,
+			Kind: defaults.PrometheusAuthKind,
+		}
+		trigger.Metadata["authModes"] = defaults.PrometheusAuthModes
+		return &trigger, nil
+	}
+
 	if v, ok := annotations[KedaAutoscaleAnnotationPrometheusAuthName]; ok {
 		ref = &v1alpha1.AuthenticationRef{}
 		ref.Name = v
@@ -268,6 +570,85 @@ func getDefaultPrometheusTrigger(annotations map[string]string, address string,
 	return &trigger, nil
 }
 
+// queryFuncs are the helper functions available to the query template.
+var queryFuncs = template.FuncMap{
+	// quote returns the value as a double quoted PromQL string.
+	"quote": strconv.Quote,
+	// regexEscape escapes the regular expression metacharacters of the value,
+	// e.g. to match it with =~. The result still needs to be quoted.
+	"regexEscape": regexp.QuoteMeta,
+	// default returns the given default if the value is empty.
+	"default": func(def string, v any) any {
+		if s, ok := v.(string); v == nil || ok && s == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// queryValues returns the values of the PA's revision the query template is executed with.
+// The PA carries the labels and annotations of its revision.
+func queryValues(pa *autoscalingv1alpha1.PodAutoscaler) map[string]any {
+	return map[string]any{
+		"revisionName":       pa.Name,
+		"namespace":          pa.Namespace,
+		"serviceName":        pa.Labels[serving.ServiceLabelKey],
+		"configurationName":  pa.Labels[serving.ConfigurationLabelKey],
+		"metricsServiceName": pa.Status.MetricsServiceName,
+		"labels":             pa.Labels,
+		"annotations":        pa.Annotations,
+	}
+}
+
+// renderQuery executes the query template with the given values.
+func renderQuery(query string, values map[string]any) (string, error) {
+	tmpl, err := template.New("query").Funcs(queryFuncs).Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("template initialization failed: %w", err)
+	}
+	var output bytes.Buffer
+	if err := tmpl.Execute(&output, values); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return output.String(), nil
+}
+
+func getScaledObjectOverride(annotations map[string]string) (*v1alpha1.ScaledObject, error) {
+	v, ok := annotations[KedaAutoscaleAnnotationsScaledObjectOverride]
+	if !ok {
+		return nil, nil
+	}
+	var sO v1alpha1.ScaledObject
+	if err := json.Unmarshal([]byte(v), &sO); err != nil {
+		return nil, err
+	}
+	return &sO, nil
+}
+
+func getScalingModifiers(annotations map[string]string) (*v1alpha1.ScalingModifiers, error) {
+	v, ok := annotations[KedaAutoscaleAnnotationScalingModifiers]
+	if !ok {
+		return nil, nil
+	}
+	var scalingModifiers v1alpha1.ScalingModifiers
+	if err := json.Unmarshal([]byte(v), &scalingModifiers); err != nil {
+		return nil, err
+	}
+	return &scalingModifiers, nil
+}
+
+func getScalingRules(annotations map[string]string, key string) (*autoscalingv2.HPAScalingRules, error) {
+	v, ok := annotations[key]
+	if !ok {
+		return nil, nil
+	}
+	var rules autoscalingv2.HPAScalingRules
+	if err := json.Unmarshal([]byte(v), &rules); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
 func getExtraPrometheusTriggers(annotations map[string]string) ([]v1alpha1.ScaleTriggers, error) {
 	triggers := []v1alpha1.ScaleTriggers(nil)
 	if v, ok := annotations[KedaAutoscaleAnnotationExtraPrometheusTriggers]; ok {
@@ -278,6 +659,47 @@ func getExtraPrometheusTriggers(annotations map[string]string) ([]v1alpha1.Scale
 	return triggers, nil
 }
 
+// renderExtraPrometheusTriggers executes the metadata of the extra triggers as templates,
+// like the query of the default trigger. Prometheus triggers without a server address
+// use the given one.
+func renderExtraPrometheusTriggers(triggers []v1alpha1.ScaleTriggers, values map[string]any, address string) error {
+	for i := range triggers {
+		t := &triggers[i]
+		for k, v := range t.Metadata {
+			rendered, err := renderQuery(v, values)
+			if err != nil {
+				return fmt.Errorf("unable to render %s of extra trigger %q: %w", k, t.Name, err)
+			}
+			t.Metadata[k] = rendered
+		}
+		if t.Type != "prometheus" {
+			continue
+		}
+		if v, ok := t.Metadata["serverAddress"]; !ok || v == "" {
+			if t.Metadata == nil {
+				t.Metadata = make(map[string]string, 1)
+			}
+			t.Metadata["serverAddress"] = address
+		} else if err := helpers.ParseServerAddress(v); err != nil {
+			return fmt.Errorf("invalid server address of extra trigger %q: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// prometheusAddress returns the Prometheus server address from the annotations or the
+// given default.
+func prometheusAddress(annotations map[string]string, defaultAddress string) (string, error) {
+	v, ok := annotations[KedaAutoscaleAnnotationPrometheusAddress]
+	if !ok {
+		return defaultAddress, nil
+	}
+	if err := helpers.ParseServerAddress(v); err != nil {
+		return "", fmt.Errorf("invalid prometheus address: %w", err)
+	}
+	return v, nil
+}
+
 func getMetricType(annotations map[string]string, metric string) (*autoscalingv2.MetricTargetType, error) {
 	var mt *autoscalingv2.MetricTargetType
 	v, ok := annotations[KedaAutoscaleAnnotationMetricType]
@@ -19,14 +19,23 @@ package resources
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	kedav1alpha1 "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/ptr"
 	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/config"
 	. "knative.dev/serving/pkg/testing" //nolint:all
 
@@ -35,28 +44,19 @@ import (
 )
 
 func TestDesiredScaledObject(t *testing.T) {
-	aConfig, err := config.NewConfigFromMap(nil)
-	if err != nil {
-		t.Fatalf("Failed to create autoscaler config = %v", err)
-	}
-
-	autoscalerKedaConfig, err := hpaconfig.NewConfigFromMap(nil)
-	if err != nil {
-		t.Fatalf("Failed to create autoscaler keda config = %v", err)
-	}
-
-	ctx := hpaconfig.ToContext(context.Background(), &hpaconfig.Config{
-		Autoscaler:     aConfig,
-		AutoscalerKeda: autoscalerKedaConfig})
+	ctx := testContext(t, nil, nil)
 	extraTrigger := fmt.Sprintf("[{\"name\": \"trigger2\", \"type\": \"prometheus\",  \"metadata\": { \"serverAddress\": \"%s\" , \"namespace\": \"%s\",  \"query\": \"sum(rate(http_requests_total{}[1m]))\", \"threshold\": \"5\"}}]", hpaconfig.DefaultPrometheusAddress, helpers.TestNamespace)
 	scalingModifiers := `{"formula": "(trigger1 + trigger2)/2", "target": "5", "activationTarget": "1", "metricType": "AverageValue"}`
+	templatedExtraTrigger := `[{"name": "trigger2", "type": "prometheus", "metadata": {"namespace": "{{ .namespace }}", "query": "sum(rate(http_requests_total{pod=~\"{{ .revisionName }}.*\"}[1m]))", "threshold": "5"}}]`
 	queryRevisionName := fmt.Sprintf("sum(rate(http_requests_total{pod=~\"%s.*\"}[1m]))", helpers.TestRevision)
 
 	scaledObjectTests := []struct {
 		name             string
 		wantErr          bool
 		wantScaledObject *kedav1alpha1.ScaledObject
-		paAnnotations    map[string]string
+		// wantTriggers are checked instead of the whole ScaledObject if set.
+		wantTriggers  []kedav1alpha1.ScaleTriggers
+		paAnnotations map[string]string
 	}{{
 		name: "cpu metric with default cm values",
 		paAnnotations: map[string]string{
@@ -83,6 +83,20 @@ func TestDesiredScaledObject(t *testing.T) {
 			KedaAutoscaleAnnotationMetricType: "Value",
 		},
 		wantErr: true,
+	}, {
+		name: "extra trigger named like the default trigger",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "cpu",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "default-trigger-cpu", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}]`,
+		},
+		wantErr: true,
+	}, {
+		name: "extra triggers with the same name",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "cpu",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "up", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}, {"name": "up", "type": "prometheus", "metadata": {"query": "max(up)", "threshold": "1"}}]`,
+		},
+		wantErr: true,
 	}, {
 		name: "cpu metric with default cm values and wrong metric type foo",
 		paAnnotations: map[string]string{
@@ -186,6 +200,77 @@ func TestDesiredScaledObject(t *testing.T) {
 				"threshold":     "5",
 				"serverAddress": "http://prometheus-operated.default.svc:9090",
 			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "cpu metric with extra triggers keeps one replica",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "cpu",
+			autoscaling.TargetAnnotationKey:                "50",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTrigger,
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTrigger,
+				autoscaling.MetricAnnotationKey:                "cpu",
+				autoscaling.TargetAnnotationKey:                "50",
+				autoscaling.ClassAnnotationKey:                 autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithCPUTrigger(map[string]string{"value": "50"}), WithTrigger("trigger2", "prometheus", "", map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         "sum(rate(http_requests_total{}[1m]))",
+				"threshold":     "5",
+				"serverAddress": "http://prometheus-operated.default.svc:9090",
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "cpu metric with extra triggers activating from zero",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "cpu",
+			autoscaling.TargetAnnotationKey:                "50",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTrigger,
+			KedaAutoscaleAnnotationActivateFromZero:        "true",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTrigger,
+				KedaAutoscaleAnnotationActivateFromZero:        "true",
+				autoscaling.MetricAnnotationKey:                "cpu",
+				autoscaling.TargetAnnotationKey:                "50",
+				autoscaling.ClassAnnotationKey:                 autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(0), WithCPUTrigger(map[string]string{"value": "50"}), WithTrigger("trigger2", "prometheus", "", map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         "sum(rate(http_requests_total{}[1m]))",
+				"threshold":     "5",
+				"serverAddress": "http://prometheus-operated.default.svc:9090",
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "cpu metric with templated extra triggers",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "cpu",
+			autoscaling.TargetAnnotationKey:                "50",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: templatedExtraTrigger,
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				KedaAutoscaleAnnotationExtraPrometheusTriggers: templatedExtraTrigger,
+				autoscaling.MetricAnnotationKey:                "cpu",
+				autoscaling.TargetAnnotationKey:                "50",
+				autoscaling.ClassAnnotationKey:                 autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithCPUTrigger(map[string]string{"value": "50"}), WithTrigger("trigger2", "prometheus", "", map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         queryRevisionName,
+				"threshold":     "5",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "extra trigger with invalid template",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"query": "sum(up{pod=\"{{ .revisionName \"})", "threshold": "5"}}]`,
+		},
+		wantErr: true,
+	}, {
+		name: "extra trigger with invalid server address",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"serverAddress": "prometheus operated", "query": "sum(up)", "threshold": "5"}}]`,
+		},
+		wantErr: true,
 	}, {
 		name: "custom metric with default cm values with extra triggers and scaling modifiers",
 		paAnnotations: map[string]string{
@@ -337,12 +422,438 @@ func TestDesiredScaledObject(t *testing.T) {
 				"threshold":     "5",
 				"serverAddress": "http://prometheus-operated.default.svc:9090",
 			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "custom metric scales to zero",
+		paAnnotations: map[string]string{
+			autoscaling.MaxScaleAnnotationKey:       "10",
+			autoscaling.MetricAnnotationKey:         "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQuery:  "sum(rate(http_requests_total{}[1m]))",
+			autoscaling.TargetAnnotationKey:         "5",
+			KedaAutoscaleAnnotationActivateFromZero: "true",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MaxScaleAnnotationKey:       "10",
+				autoscaling.MetricAnnotationKey:         "http_requests_total",
+				KedaAutoscaleAnnotationPrometheusQuery:  "sum(rate(http_requests_total{}[1m]))",
+				autoscaling.TargetAnnotationKey:         "5",
+				KedaAutoscaleAnnotationActivateFromZero: "true",
+				autoscaling.ClassAnnotationKey:          autoscaling.HPA,
+			}), WithMaxScale(10), WithMinScale(0), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         "sum(rate(http_requests_total{}[1m]))",
+				"threshold":     "5",
+				"serverAddress": "http://prometheus-operated.default.svc:9090",
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "rps metric with target utilization",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:            autoscaling.RPS,
+			autoscaling.TargetAnnotationKey:            "10",
+			autoscaling.TargetUtilizationPercentageKey: "55",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:            autoscaling.RPS,
+				autoscaling.TargetAnnotationKey:            "10",
+				autoscaling.TargetUtilizationPercentageKey: "55",
+				autoscaling.ClassAnnotationKey:             autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(0), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace": helpers.TestNamespace,
+				"query": fmt.Sprintf(`(sum(avg_over_time(queue_requests_per_second{destination_namespace="%s", destination_revision="%s"}[60s])) or vector(0)) - (sum(avg_over_time(queue_proxied_operations_per_second{destination_namespace="%s", destination_revision="%s"}[60s])) or vector(0)) + (sum(rate(activator_request_count{namespace_name="%s", revision_name="%s"}[60s])) or vector(0))`,
+					helpers.TestNamespace, helpers.TestRevision, helpers.TestNamespace, helpers.TestRevision, helpers.TestNamespace, helpers.TestRevision),
+				"threshold":     "5.5",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "concurrency metric with default target",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey: autoscaling.Concurrency,
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey: autoscaling.Concurrency,
+				autoscaling.ClassAnnotationKey:  autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(0), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace": helpers.TestNamespace,
+				"query": fmt.Sprintf(`(sum(queue_average_concurrent_requests{destination_namespace="%s", destination_revision="%s"}) or vector(0)) - (sum(queue_average_proxied_concurrent_requests{destination_namespace="%s", destination_revision="%s"}) or vector(0)) + (sum(activator_request_concurrency{namespace_name="%s", revision_name="%s"}) or vector(0))`,
+					helpers.TestNamespace, helpers.TestRevision, helpers.TestNamespace, helpers.TestRevision, helpers.TestNamespace, helpers.TestRevision),
+				"threshold":     "70",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "concurrency metric with custom query keeps one replica",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        autoscaling.Concurrency,
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(inflight_requests)",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:        autoscaling.Concurrency,
+				KedaAutoscaleAnnotationPrometheusQuery: "sum(inflight_requests)",
+				autoscaling.ClassAnnotationKey:         autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         "sum(inflight_requests)",
+				"threshold":     "70",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "custom metric with built query",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                  "http_requests_total",
+			autoscaling.TargetAnnotationKey:                  "5",
+			KedaAutoscaleAnnotationPrometheusQueryMetric:     "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryRateWindow: "1m",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:                  "http_requests_total",
+				autoscaling.TargetAnnotationKey:                  "5",
+				KedaAutoscaleAnnotationPrometheusQueryMetric:     "http_requests_total",
+				KedaAutoscaleAnnotationPrometheusQueryRateWindow: "1m",
+				autoscaling.ClassAnnotationKey:                   autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         fmt.Sprintf(`sum(rate(http_requests_total{namespace="%s", pod=~"%s-deployment-.*"}[1m]))`, helpers.TestNamespace, helpers.TestRevision),
+				"threshold":     "5",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "custom metric with invalid built query",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:              "http_requests_total",
+			autoscaling.TargetAnnotationKey:              "5",
+			KedaAutoscaleAnnotationPrometheusQueryMetric: "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryLabels: `code=`,
+		},
+		wantErr: true,
+	}, {
+		name: "concurrency metric with custom query",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        autoscaling.Concurrency,
+			autoscaling.TargetAnnotationKey:        "20",
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(http_requests_in_flight{}",
+		},
+		wantErr: true,
+	}, {
+		name: "custom metric with invalid query",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m])",
+			autoscaling.TargetAnnotationKey:        "5",
+		},
+		wantErr: true,
+	}, {
+		name: "extra trigger with invalid query",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"query": "http_requests_total[1m]", "threshold": "5"}}]`,
+		},
+		wantErr: true,
+	}, {
+		name: "cpu metric with target latency",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:            "cpu",
+			autoscaling.TargetAnnotationKey:            "75",
+			KedaAutoscaleAnnotationTargetLatency:       "250ms",
+			KedaAutoscaleAnnotationTargetLatencyMetric: "http_request_duration_seconds",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:            "cpu",
+				autoscaling.TargetAnnotationKey:            "75",
+				KedaAutoscaleAnnotationTargetLatency:       "250ms",
+				KedaAutoscaleAnnotationTargetLatencyMetric: "http_request_duration_seconds",
+				autoscaling.ClassAnnotationKey:             autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithScaleTargetRef(helpers.TestRevision+"-deployment"),
+			WithTrigger("default-trigger-cpu", "cpu", autoscalingv2.UtilizationMetricType, map[string]string{
+				"value": "75",
+			}), WithTrigger("latency", "prometheus", autoscalingv2.ValueMetricType, map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         fmt.Sprintf(`histogram_quantile(0.95, sum by (le) (rate(http_request_duration_seconds_bucket{namespace="%s", pod=~"%s-deployment-.*"}[1m])))`, helpers.TestNamespace, helpers.TestRevision),
+				"threshold":     "0.25",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScalingModifiers(kedav1alpha1.ScalingModifiers{
+				Formula:    "latency / 0.25",
+				Target:     "1",
+				MetricType: autoscalingv2.ValueMetricType,
+			}), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "target latency with a custom metric",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:            "http_requests_total",
+			autoscaling.TargetAnnotationKey:            "5",
+			KedaAutoscaleAnnotationPrometheusQuery:     "sum(rate(http_requests_total{}[1m]))",
+			KedaAutoscaleAnnotationTargetLatency:       "250ms",
+			KedaAutoscaleAnnotationTargetLatencyMetric: "http_request_duration_seconds",
+		},
+		wantErr: true,
+	}, {
+		name: "cpu metric with polling interval",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "cpu",
+			autoscaling.TargetAnnotationKey:        "75",
+			KedaAutoscaleAnnotationPollingInterval: "1m",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:        "cpu",
+				autoscaling.TargetAnnotationKey:        "75",
+				KedaAutoscaleAnnotationPollingInterval: "1m",
+				autoscaling.ClassAnnotationKey:         autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithScaleTargetRef(helpers.TestRevision+"-deployment"),
+			WithTrigger("default-trigger-cpu", "cpu", autoscalingv2.UtilizationMetricType, map[string]string{
+				"value": "75",
+			}), WithHorizontalPodAutoscalerConfig(helpers.TestRevision), func(sO *kedav1alpha1.ScaledObject) {
+				sO.Spec.PollingInterval = ptr.Int32(60)
+			}),
+	}, {
+		name: "custom metric with spec annotations",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                      "http_requests_total",
+			autoscaling.TargetAnnotationKey:                      "5",
+			KedaAutoscaleAnnotationPrometheusQuery:               "sum(rate(http_requests_total{}[1m]))",
+			autoscaling.MinScaleAnnotationKey:                    "2",
+			KedaAutoscaleAnnotationCooldownPeriod:                "2m",
+			KedaAutoscaleAnnotationInitialCooldownPeriod:         "30s",
+			KedaAutoscaleAnnotationIdleReplicaCount:              "0",
+			KedaAutoscaleAnnotationFallbackFailureThreshold:      "3",
+			KedaAutoscaleAnnotationFallbackReplicas:              "4",
+			KedaAutoscaleAnnotationRestoreToOriginalReplicaCount: "true",
+		},
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				autoscaling.MetricAnnotationKey:                      "http_requests_total",
+				autoscaling.TargetAnnotationKey:                      "5",
+				KedaAutoscaleAnnotationPrometheusQuery:               "sum(rate(http_requests_total{}[1m]))",
+				autoscaling.MinScaleAnnotationKey:                    "2",
+				KedaAutoscaleAnnotationCooldownPeriod:                "2m",
+				KedaAutoscaleAnnotationInitialCooldownPeriod:         "30s",
+				KedaAutoscaleAnnotationIdleReplicaCount:              "0",
+				KedaAutoscaleAnnotationFallbackFailureThreshold:      "3",
+				KedaAutoscaleAnnotationFallbackReplicas:              "4",
+				KedaAutoscaleAnnotationRestoreToOriginalReplicaCount: "true",
+				autoscaling.ClassAnnotationKey:                       autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(2), WithTrigger("default-trigger-custom", "prometheus", autoscalingv2.AverageValueMetricType, map[string]string{
+				"namespace":     helpers.TestNamespace,
+				"query":         "sum(rate(http_requests_total{}[1m]))",
+				"threshold":     "5",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			}), WithScaleTargetRef(helpers.TestRevision+"-deployment"), WithHorizontalPodAutoscalerConfig(helpers.TestRevision),
+			func(sO *kedav1alpha1.ScaledObject) {
+				sO.Spec.CooldownPeriod = ptr.Int32(120)
+				sO.Spec.InitialCooldownPeriod = 30
+				sO.Spec.IdleReplicaCount = ptr.Int32(0)
+				sO.Spec.Fallback = &kedav1alpha1.Fallback{FailureThreshold: 3, Replicas: 4}
+				sO.Spec.Advanced.RestoreToOriginalReplicaCount = true
+			}),
+	}, {
+		name: "cpu metric with fallback",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                 "cpu",
+			KedaAutoscaleAnnotationFallbackFailureThreshold: "3",
+			KedaAutoscaleAnnotationFallbackReplicas:         "4",
+		},
+		wantErr: true,
+	}, {
+		name: "idle replica count without min scale",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:         "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQuery:  "sum(rate(http_requests_total{}[1m]))",
+			autoscaling.TargetAnnotationKey:         "5",
+			KedaAutoscaleAnnotationActivateFromZero: "true",
+			KedaAutoscaleAnnotationIdleReplicaCount: "0",
+		},
+		wantErr: true,
+	}, {
+		name: "idle replica count not zero",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:         "cpu",
+			autoscaling.MinScaleAnnotationKey:       "3",
+			KedaAutoscaleAnnotationIdleReplicaCount: "1",
+		},
+		wantErr: true,
+	}, {
+		name: "target latency without metric",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTargetLatency: "250ms",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation triggers of rabbitmq and prometheus",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":                "rabbitmq",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metadata.queueName":  "{{ .revisionName }}",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metadata.mode":       "QueueLength",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metadata.value":      "20",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.auth-name":           "rabbitmq-auth",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.auth-kind":           "ClusterTriggerAuthentication",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.type":               "prometheus",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.metric-type":        "Value",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.metadata.query":     `sum(rate(http_errors_total{namespace="{{ .namespace }}"}[1m]))`,
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.metadata.threshold": "1",
+		},
+		wantTriggers: []kedav1alpha1.ScaleTriggers{{
+			Type:       "cpu",
+			Name:       "default-trigger-cpu",
+			MetricType: autoscalingv2.UtilizationMetricType,
+			Metadata:   map[string]string{"value": "70"},
+		}, {
+			Type:       "prometheus",
+			Name:       "errors",
+			MetricType: autoscalingv2.ValueMetricType,
+			Metadata: map[string]string{
+				"query":         fmt.Sprintf(`sum(rate(http_errors_total{namespace="%s"}[1m]))`, helpers.TestNamespace),
+				"threshold":     "1",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			},
+		}, {
+			Type: "rabbitmq",
+			Name: "queue",
+			Metadata: map[string]string{
+				"queueName": helpers.TestRevision,
+				"mode":      "QueueLength",
+				"value":     "20",
+			},
+			AuthenticationRef: &kedav1alpha1.AuthenticationRef{Name: "rabbitmq-auth", Kind: "ClusterTriggerAuthentication"},
+		}},
+	}, {
+		name: "annotation trigger of an unknown scaler",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type": "rabbit",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation trigger without type",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metadata.queueName": "orders",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation trigger with an unknown field",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":  "rabbitmq",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.value": "20",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation trigger with utilization of a non resource scaler",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":        "rabbitmq",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metric-type": "Utilization",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation trigger with auth kind without name",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":      "rabbitmq",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.auth-kind": "TriggerAuthentication",
+		},
+		wantErr: true,
+	}, {
+		name: "annotation trigger named like the default trigger",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "default-trigger-cpu.type":           "cpu",
+			KedaAutoscaleAnnotationTriggerPrefix + "default-trigger-cpu.metric-type":    "Utilization",
+			KedaAutoscaleAnnotationTriggerPrefix + "default-trigger-cpu.metadata.value": "50",
+		},
+		wantErr: true,
+	}, {
+		name: "schedule during business hours",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationScheduleStart:           "0 8 * * 1-5",
+			KedaAutoscaleAnnotationScheduleEnd:             "0 18 * * 1-5",
+			KedaAutoscaleAnnotationScheduleDesiredReplicas: "5",
+			KedaAutoscaleAnnotationScheduleTimezone:        "Europe/Berlin",
+		},
+		// Nothing would activate the revision from zero outside of the window.
+		wantScaledObject: ScaledObject(helpers.TestNamespace,
+			helpers.TestRevision, WithAnnotations(map[string]string{
+				KedaAutoscaleAnnotationScheduleStart:           "0 8 * * 1-5",
+				KedaAutoscaleAnnotationScheduleEnd:             "0 18 * * 1-5",
+				KedaAutoscaleAnnotationScheduleDesiredReplicas: "5",
+				KedaAutoscaleAnnotationScheduleTimezone:        "Europe/Berlin",
+				autoscaling.ClassAnnotationKey:                 autoscaling.HPA,
+			}), WithMaxScale(math.MaxInt32), WithMinScale(1), WithScaleTargetRef(helpers.TestRevision+"-deployment"),
+			WithTrigger("default-trigger-cpu", "cpu", autoscalingv2.UtilizationMetricType, map[string]string{
+				"value": "70",
+			}), WithTrigger("schedule", "cron", "", map[string]string{
+				"timezone":        "Europe/Berlin",
+				"start":           "0 8 * * 1-5",
+				"end":             "0 18 * * 1-5",
+				"desiredReplicas": "5",
+			}), WithHorizontalPodAutoscalerConfig(helpers.TestRevision)),
+	}, {
+		name: "several schedule windows",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationSchedules: `[{"name": "morning", "start": "0 8 * * *", "end": "0 12 * * *", "desiredReplicas": 10},
+				{"name": "evening", "start": "0 18 * * *", "end": "0 22 * * *", "desiredReplicas": 4, "timezone": "America/New_York"}]`,
+		},
+		wantTriggers: []kedav1alpha1.ScaleTriggers{{
+			Type:       "cpu",
+			Name:       "default-trigger-cpu",
+			MetricType: autoscalingv2.UtilizationMetricType,
+			Metadata:   map[string]string{"value": "70"},
+		}, {
+			Type: "cron",
+			Name: "schedule-morning",
+			Metadata: map[string]string{
+				"timezone":        "Etc/UTC",
+				"start":           "0 8 * * *",
+				"end":             "0 12 * * *",
+				"desiredReplicas": "10",
+			},
+		}, {
+			Type: "cron",
+			Name: "schedule-evening",
+			Metadata: map[string]string{
+				"timezone":        "America/New_York",
+				"start":           "0 18 * * *",
+				"end":             "0 22 * * *",
+				"desiredReplicas": "4",
+			},
+		}},
+	}, {
+		name: "incomplete schedule window",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationScheduleStart: "0 8 * * 1-5",
+		},
+		wantErr: true,
+	}, {
+		name: "schedule with an invalid cron expression",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationScheduleStart:           "0 8 * * MON-FRI *",
+			KedaAutoscaleAnnotationScheduleEnd:             "0 18 * * 1-5",
+			KedaAutoscaleAnnotationScheduleDesiredReplicas: "5",
+		},
+		wantErr: true,
+	}, {
+		name: "schedule with an invalid timezone",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationScheduleStart:           "0 8 * * 1-5",
+			KedaAutoscaleAnnotationScheduleEnd:             "0 18 * * 1-5",
+			KedaAutoscaleAnnotationScheduleDesiredReplicas: "5",
+			KedaAutoscaleAnnotationScheduleTimezone:        "Mars/Olympus_Mons",
+		},
+		wantErr: true,
+	}, {
+		name: "schedule window without name",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationSchedules: `[{"start": "0 8 * * *", "end": "0 12 * * *", "desiredReplicas": 10}]`,
+		},
+		wantErr: true,
+	}, {
+		name: "schedule window without desired replicas",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationSchedules: `[{"name": "morning", "start": "0 8 * * *", "end": "0 12 * * *"}]`,
+		},
+		wantErr: true,
 	}}
 
 	for _, tt := range scaledObjectTests {
 		t.Run(tt.name, func(t *testing.T) {
 			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(tt.paAnnotations))
-			scaledObject, err := DesiredScaledObject(ctx, pa)
+			scaledObject, err := DesiredScaledObject(ctx, pa, nil)
 			if tt.wantScaledObject != nil {
 				tt.wantScaledObject.Spec.ScaleTargetRef.Name = pa.Spec.ScaleTargetRef.Name
 				tt.wantScaledObject.Spec.ScaleTargetRef.Kind = pa.Spec.ScaleTargetRef.Kind
@@ -350,6 +861,10 @@ func TestDesiredScaledObject(t *testing.T) {
 			}
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Failed to create desiredScaledObject, error = %v, want: %v", err, tt.wantErr)
+			} else if err == nil && tt.wantTriggers != nil {
+				if diff := cmp.Diff(tt.wantTriggers, scaledObject.Spec.Triggers); diff != "" {
+					t.Fatalf("Triggers mismatch: diff(-want,+got):\n%s", diff)
+				}
 			} else if err == nil {
 				tt.wantScaledObject.OwnerReferences = []v1.OwnerReference{*kmeta.NewControllerRef(pa)}
 				if diff := cmp.Diff(tt.wantScaledObject, scaledObject); diff != "" {
@@ -359,3 +874,698 @@ func TestDesiredScaledObject(t *testing.T) {
 		})
 	}
 }
+
+func TestDesiredScaledObjectScaleToZeroDisabled(t *testing.T) {
+	ctx := testContext(t, map[string]string{
+		"enable-scale-to-zero": "false",
+	}, nil)
+
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(map[string]string{
+		autoscaling.MetricAnnotationKey:        "http_requests_total",
+		KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m]))",
+		autoscaling.TargetAnnotationKey:        "5",
+	}))
+	scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+	if err != nil {
+		t.Fatalf("Failed to create desiredScaledObject, error = %v", err)
+	}
+	if got := scaledObject.Spec.MinReplicaCount; got == nil || *got != 1 {
+		t.Errorf("MinReplicaCount = %v, want 1", got)
+	}
+}
+
+func TestDesiredScaledObjectWithoutKedaConfig(t *testing.T) {
+	aConfig, err := config.NewConfigFromMap(nil)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler config = %v", err)
+	}
+	ctx := hpaconfig.ToContext(context.Background(), &hpaconfig.Config{Autoscaler: aConfig})
+
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(map[string]string{
+		autoscaling.MetricAnnotationKey:              "http_requests_total",
+		KedaAutoscaleAnnotationPrometheusQuery:       "sum(rate(http_requests_total{}[1m]))",
+		autoscaling.TargetAnnotationKey:              "5",
+		KedaAutoscaleAnnotationKafkaBootstrapServers: "kafka:9092",
+		KedaAutoscaleAnnotationKafkaConsumerGroup:    "orders",
+	}))
+	scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+	if err != nil {
+		t.Fatalf("Failed to create desiredScaledObject, error = %v", err)
+	}
+	if got := scaledObject.Spec.Triggers[0].Metadata["serverAddress"]; got != hpaconfig.DefaultPrometheusAddress {
+		t.Errorf("serverAddress = %s, want: %s", got, hpaconfig.DefaultPrometheusAddress)
+	}
+	if got := len(scaledObject.Spec.Triggers); got != 2 {
+		t.Errorf("len(Triggers) = %d, want: 2", got)
+	}
+}
+
+func TestDesiredScaledObjectDefaultAuth(t *testing.T) {
+	ctx := testContext(t, nil, map[string]string{
+		"autoscaler.keda.prometheus-auth-name":  "keda-trigger-auth-prometheus",
+		"autoscaler.keda.prometheus-auth-kind":  "ClusterTriggerAuthentication",
+		"autoscaler.keda.prometheus-auth-modes": "bearer",
+	})
+
+	tests := []struct {
+		name          string
+		paAnnotations map[string]string
+		wantRef       *kedav1alpha1.AuthenticationRef
+		wantModes     string
+	}{{
+		name: "default auth",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "http_requests_total",
+			autoscaling.TargetAnnotationKey:        "5",
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m]))",
+		},
+		wantRef:   &kedav1alpha1.AuthenticationRef{Name: "keda-trigger-auth-prometheus", Kind: "ClusterTriggerAuthentication"},
+		wantModes: "bearer",
+	}, {
+		name: "revision auth",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:            "http_requests_total",
+			autoscaling.TargetAnnotationKey:            "5",
+			KedaAutoscaleAnnotationPrometheusQuery:     "sum(rate(http_requests_total{}[1m]))",
+			KedaAutoscaleAnnotationPrometheusAuthName:  "revision-auth",
+			KedaAutoscaleAnnotationPrometheusAuthModes: "basic",
+		},
+		wantRef:   &kedav1alpha1.AuthenticationRef{Name: "revision-auth"},
+		wantModes: "basic",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(tt.paAnnotations))
+			scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+			if err != nil {
+				t.Fatal("DesiredScaledObject() =", err)
+			}
+			trigger := scaledObject.Spec.Triggers[0]
+			if diff := cmp.Diff(tt.wantRef, trigger.AuthenticationRef); diff != "" {
+				t.Errorf("AuthenticationRef mismatch: diff(-want,+got):\n%s", diff)
+			}
+			if got := trigger.Metadata["authModes"]; got != tt.wantModes {
+				t.Errorf("authModes = %s, want: %s", got, tt.wantModes)
+			}
+		})
+	}
+}
+
+func TestDesiredScaledObjectConfigDefaults(t *testing.T) {
+	ctx := testContext(t, nil, map[string]string{
+		"autoscaler.keda.polling-interval":           "15s",
+		"autoscaler.keda.cooldown-period":            "1m",
+		"autoscaler.keda.initial-cooldown-period":    "2m",
+		"autoscaler.keda.fallback-failure-threshold": "3",
+		"autoscaler.keda.fallback-replicas":          "2",
+	})
+
+	tests := []struct {
+		name                string
+		paAnnotations       map[string]string
+		wantPollingInterval int32
+		wantFallback        *kedav1alpha1.Fallback
+	}{{
+		name: "custom metric",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "http_requests_total",
+			autoscaling.TargetAnnotationKey:        "5",
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m]))",
+		},
+		wantPollingInterval: 15,
+		wantFallback:        &kedav1alpha1.Fallback{FailureThreshold: 3, Replicas: 2},
+	}, {
+		name: "cpu metric does not support fallback",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey: "cpu",
+		},
+		wantPollingInterval: 15,
+	}, {
+		name: "revision polling interval",
+		paAnnotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "http_requests_total",
+			autoscaling.TargetAnnotationKey:        "5",
+			KedaAutoscaleAnnotationPrometheusQuery: "sum(rate(http_requests_total{}[1m]))",
+			KedaAutoscaleAnnotationPollingInterval: "5s",
+		},
+		wantPollingInterval: 5,
+		wantFallback:        &kedav1alpha1.Fallback{FailureThreshold: 3, Replicas: 2},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(tt.paAnnotations))
+			scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+			if err != nil {
+				t.Fatal("DesiredScaledObject() =", err)
+			}
+			if got := scaledObject.Spec.PollingInterval; got == nil || *got != tt.wantPollingInterval {
+				t.Errorf("PollingInterval = %v, want: %d", got, tt.wantPollingInterval)
+			}
+			if got := scaledObject.Spec.CooldownPeriod; got == nil || *got != 60 {
+				t.Errorf("CooldownPeriod = %v, want: 60", got)
+			}
+			if got := scaledObject.Spec.InitialCooldownPeriod; got != 120 {
+				t.Errorf("InitialCooldownPeriod = %d, want: 120", got)
+			}
+			if diff := cmp.Diff(tt.wantFallback, scaledObject.Spec.Fallback); diff != "" {
+				t.Errorf("Fallback mismatch: diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDesiredScaledObjectPatch(t *testing.T) {
+	aConfig, err := config.NewConfigFromMap(nil)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler config = %v", err)
+	}
+
+	autoscalerKedaConfig, err := hpaconfig.NewConfigFromMap(nil)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler keda config = %v", err)
+	}
+
+	ctx := hpaconfig.ToContext(context.Background(), &hpaconfig.Config{
+		Autoscaler:     aConfig,
+		AutoscalerKeda: autoscalerKedaConfig})
+
+	annotations := map[string]string{
+		autoscaling.MetricAnnotationKey:                "http_requests_total",
+		autoscaling.TargetAnnotationKey:                "5",
+		autoscaling.WindowAnnotationKey:                "30s",
+		KedaAutoscaleAnnotationPrometheusQuery:         "sum(rate(http_requests_total{}[1m]))",
+		KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}]`,
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    func(*kedav1alpha1.ScaledObject)
+		wantErr bool
+	}{{
+		name:  "patch a field",
+		patch: `{"spec": {"pollingInterval": 5, "advanced": {"restoreToOriginalReplicaCount": true}}}`,
+		want: func(sO *kedav1alpha1.ScaledObject) {
+			sO.Spec.PollingInterval = ptr.Int32(5)
+			sO.Spec.Advanced.RestoreToOriginalReplicaCount = true
+		},
+	}, {
+		name:  "patch triggers by name",
+		patch: `{"spec": {"triggers": [{"name": "default-trigger-custom", "metadata": {"threshold": "10"}}, {"name": "trigger2", "$patch": "delete"}, {"name": "queue", "type": "rabbitmq", "metadata": {"queueName": "orders", "mode": "QueueLength", "value": "20"}}]}}`,
+		want: func(sO *kedav1alpha1.ScaledObject) {
+			// The rabbitmq trigger activates the revision from zero.
+			sO.Spec.MinReplicaCount = ptr.Int32(0)
+			sO.Spec.Triggers[0].Metadata["threshold"] = "10"
+			sO.Spec.Triggers[1] = kedav1alpha1.ScaleTriggers{
+				Name: "queue",
+				Type: "rabbitmq",
+				Metadata: map[string]string{
+					"queueName": "orders",
+					"mode":      "QueueLength",
+					"value":     "20",
+				},
+			}
+		},
+	}, {
+		name:  "patched min replicas are kept",
+		patch: `{"spec": {"minReplicaCount": 3, "triggers": [{"name": "cron", "type": "cron", "metadata": {"timezone": "UTC", "start": "0 8 * * *", "end": "0 18 * * *", "desiredReplicas": "3"}}]}}`,
+		want: func(sO *kedav1alpha1.ScaledObject) {
+			sO.Spec.MinReplicaCount = ptr.Int32(3)
+			sO.Spec.Triggers = append(sO.Spec.Triggers, kedav1alpha1.ScaleTriggers{
+				Name: "cron",
+				Type: "cron",
+				Metadata: map[string]string{
+					"timezone":        "UTC",
+					"start":           "0 8 * * *",
+					"end":             "0 18 * * *",
+					"desiredReplicas": "3",
+				},
+			})
+		},
+	}, {
+		name:    "min replicas of zero with only cpu triggers",
+		patch:   `{"spec": {"minReplicaCount": null, "triggers": [{"name": "default-trigger-custom", "$patch": "delete"}, {"name": "trigger2", "$patch": "delete"}, {"name": "cpu", "type": "cpu", "metricType": "Utilization", "metadata": {"value": "80"}}]}}`,
+		wantErr: true,
+	}, {
+		name:  "identity is kept",
+		patch: `{"metadata": {"name": "other", "ownerReferences": null}, "spec": {"scaleTargetRef": {"name": "other"}}}`,
+		want:  func(*kedav1alpha1.ScaledObject) {},
+	}, {
+		name:    "delete unknown trigger",
+		patch:   `{"spec": {"triggers": [{"name": "trigger3", "$patch": "delete"}]}}`,
+		wantErr: true,
+	}, {
+		name:    "trigger without name",
+		patch:   `{"spec": {"triggers": [{"metadata": {"threshold": "10"}}]}}`,
+		wantErr: true,
+	}, {
+		name:    "invalid json",
+		patch:   `{"spec": `,
+		wantErr: true,
+	}, {
+		name:    "invalid query",
+		patch:   `{"spec": {"triggers": [{"name": "trigger2", "metadata": {"query": "sum(up"}}]}}`,
+		wantErr: true,
+	}, {
+		name:    "no triggers left",
+		patch:   `{"spec": {"triggers": [{"name": "default-trigger-custom", "$patch": "delete"}, {"name": "trigger2", "$patch": "delete"}]}}`,
+		wantErr: true,
+	}, {
+		name:    "idle replicas not zero",
+		patch:   `{"spec": {"minReplicaCount": 2, "idleReplicaCount": 1}}`,
+		wantErr: true,
+	}, {
+		name:    "idle replicas without min replicas",
+		patch:   `{"spec": {"minReplicaCount": 0, "idleReplicaCount": 0}}`,
+		wantErr: true,
+	}, {
+		name:  "idle replicas",
+		patch: `{"spec": {"minReplicaCount": 2, "idleReplicaCount": 0}}`,
+		want: func(sO *kedav1alpha1.ScaledObject) {
+			sO.Spec.MinReplicaCount = ptr.Int32(2)
+			sO.Spec.IdleReplicaCount = ptr.Int32(0)
+		},
+	}, {
+		name:    "fallback without failure threshold",
+		patch:   `{"spec": {"fallback": {"replicas": 3}, "triggers": [{"name": "trigger2", "metricType": "AverageValue"}]}}`,
+		wantErr: true,
+	}, {
+		name:    "fallback with a trigger of type Value",
+		patch:   `{"spec": {"fallback": {"failureThreshold": 3, "replicas": 3}}}`,
+		wantErr: true,
+	}, {
+		name:  "fallback",
+		patch: `{"spec": {"fallback": {"failureThreshold": 3, "replicas": 3}, "triggers": [{"name": "trigger2", "metricType": "AverageValue"}]}}`,
+		want: func(sO *kedav1alpha1.ScaledObject) {
+			sO.Spec.Triggers[1].MetricType = autoscalingv2.AverageValueMetricType
+			sO.Spec.Fallback = &kedav1alpha1.Fallback{FailureThreshold: 3, Replicas: 3}
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(annotations))
+			want, err := DesiredScaledObject(ctx, pa, nil)
+			if err != nil {
+				t.Fatal("DesiredScaledObject() =", err)
+			}
+
+			pa = helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(annotations),
+				helpers.WithAnnotations(map[string]string{KedaAutoscaleAnnotationScaledObjectPatch: tt.patch}))
+			got, err := DesiredScaledObject(ctx, pa, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DesiredScaledObject() = %v, want error: %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			tt.want(want)
+			if diff := cmp.Diff(want.Spec, got.Spec); diff != "" {
+				t.Errorf("Spec mismatch: diff(-want,+got):\n%s", diff)
+			}
+			if got.Name != helpers.TestRevision || len(got.OwnerReferences) != 1 {
+				t.Errorf("ScaledObject identity = %s %v, want the generated one", got.Name, got.OwnerReferences)
+			}
+		})
+	}
+}
+
+func TestDesiredScaledObjectTriggerTemplate(t *testing.T) {
+	ctx := testContext(t, nil, map[string]string{
+		hpaconfig.TriggerTemplateKeyPrefix + "http-rate": `{"type": "prometheus", "metricType": "AverageValue", "metadata": {"query": "sum(rate({{ .params.metric }}{namespace=\"{{ .namespace }}\"}[1m]))", "threshold": "{{ index .params \"threshold\" | default \"10\" }}"}, "authenticationRef": {"name": "keda-trigger-auth-prometheus"}}`,
+	})
+
+	tests := []struct {
+		name          string
+		paAnnotations map[string]string
+		wantTrigger   *kedav1alpha1.ScaleTriggers
+		wantErr       bool
+	}{{
+		name: "template with params",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:       "http-rate",
+			KedaAutoscaleAnnotationTriggerTemplateParams: `{"metric": "http_requests_total", "threshold": "5"}`,
+		},
+		wantTrigger: &kedav1alpha1.ScaleTriggers{
+			Type:       "prometheus",
+			Name:       "http-rate",
+			MetricType: autoscalingv2.AverageValueMetricType,
+			Metadata: map[string]string{
+				"query":         fmt.Sprintf(`sum(rate(http_requests_total{namespace="%s"}[1m]))`, helpers.TestNamespace),
+				"threshold":     "5",
+				"serverAddress": hpaconfig.DefaultPrometheusAddress,
+			},
+			AuthenticationRef: &kedav1alpha1.AuthenticationRef{Name: "keda-trigger-auth-prometheus"},
+		},
+	}, {
+		name: "template with default params",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:       "http-rate",
+			KedaAutoscaleAnnotationTriggerTemplateParams: `{"metric": "http_requests_total"}`,
+			KedaAutoscaleAnnotationPrometheusAddress:     "http://thanos:9090",
+		},
+		wantTrigger: &kedav1alpha1.ScaleTriggers{
+			Type:       "prometheus",
+			Name:       "http-rate",
+			MetricType: autoscalingv2.AverageValueMetricType,
+			Metadata: map[string]string{
+				"query":         fmt.Sprintf(`sum(rate(http_requests_total{namespace="%s"}[1m]))`, helpers.TestNamespace),
+				"threshold":     "10",
+				"serverAddress": "http://thanos:9090",
+			},
+			AuthenticationRef: &kedav1alpha1.AuthenticationRef{Name: "keda-trigger-auth-prometheus"},
+		},
+	}, {
+		name: "template named like an extra trigger",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:         "http-rate",
+			KedaAutoscaleAnnotationTriggerTemplateParams:   `{"metric": "http_requests_total"}`,
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "http-rate", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}]`,
+		},
+		wantErr: true,
+	}, {
+		name: "unknown template",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate: "kafka-lag",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid params",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:       "http-rate",
+			KedaAutoscaleAnnotationTriggerTemplateParams: `{"metric": 5}`,
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(tt.paAnnotations))
+			scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DesiredScaledObject() = %v, want error: %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			triggers := scaledObject.Spec.Triggers
+			if diff := cmp.Diff(tt.wantTrigger, &triggers[len(triggers)-1]); diff != "" {
+				t.Errorf("Template trigger mismatch: diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	// The template is rendered on a copy, leaving the config untouched for the other revisions.
+	if got := hpaconfig.FromContext(ctx).AutoscalerKeda.TriggerTemplates["http-rate"].Metadata["threshold"]; got != `{{ index .params "threshold" | default "10" }}` {
+		t.Errorf("Template threshold = %s, want it unrendered", got)
+	}
+}
+
+func TestDesiredScaledObjectKafka(t *testing.T) {
+	aConfig, err := config.NewConfigFromMap(nil)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler config = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		configData    map[string]string
+		paAnnotations map[string]string
+		wantTrigger   *kedav1alpha1.ScaleTriggers
+		wantErr       bool
+	}{{
+		name: "consumer lag",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaBootstrapServers:       "my-cluster-kafka-bootstrap.kafka:9092",
+			KedaAutoscaleAnnotationKafkaTopic:                  "orders",
+			KedaAutoscaleAnnotationKafkaConsumerGroup:          "{{ .namespace }}-{{ .serviceName }}",
+			KedaAutoscaleAnnotationKafkaLagThreshold:           "50",
+			KedaAutoscaleAnnotationKafkaActivationLagThreshold: "0",
+			KedaAutoscaleAnnotationKafkaAuthName:               "kafka-auth",
+			KedaAutoscaleAnnotationKafkaAuthKind:               "ClusterTriggerAuthentication",
+		},
+		wantTrigger: &kedav1alpha1.ScaleTriggers{
+			Type:       "kafka",
+			Name:       "kafka",
+			MetricType: autoscalingv2.AverageValueMetricType,
+			Metadata: map[string]string{
+				"bootstrapServers":       "my-cluster-kafka-bootstrap.kafka:9092",
+				"topic":                  "orders",
+				"consumerGroup":          helpers.TestNamespace + "-test-service",
+				"lagThreshold":           "50",
+				"activationLagThreshold": "0",
+			},
+			AuthenticationRef: &kedav1alpha1.AuthenticationRef{Name: "kafka-auth", Kind: "ClusterTriggerAuthentication"},
+		},
+	}, {
+		name:       "default bootstrap servers",
+		configData: map[string]string{"autoscaler.keda.kafka-bootstrap-servers": "kafka-0:9092,kafka-1:9092"},
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaConsumerGroup: "orders",
+		},
+		wantTrigger: &kedav1alpha1.ScaleTriggers{
+			Type:       "kafka",
+			Name:       "kafka",
+			MetricType: autoscalingv2.AverageValueMetricType,
+			Metadata: map[string]string{
+				"bootstrapServers": "kafka-0:9092,kafka-1:9092",
+				"consumerGroup":    "orders",
+			},
+		},
+	}, {
+		name: "missing bootstrap servers",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaConsumerGroup: "orders",
+		},
+		wantErr: true,
+	}, {
+		name: "missing consumer group",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaBootstrapServers: "kafka:9092",
+			KedaAutoscaleAnnotationKafkaTopic:            "orders",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid lag threshold",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaBootstrapServers: "kafka:9092",
+			KedaAutoscaleAnnotationKafkaConsumerGroup:    "orders",
+			KedaAutoscaleAnnotationKafkaLagThreshold:     "0",
+		},
+		wantErr: true,
+	}, {
+		name: "auth kind without name",
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaBootstrapServers: "kafka:9092",
+			KedaAutoscaleAnnotationKafkaConsumerGroup:    "orders",
+			KedaAutoscaleAnnotationKafkaAuthKind:         "TriggerAuthentication",
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			autoscalerKedaConfig, err := hpaconfig.NewConfigFromMap(tt.configData)
+			if err != nil {
+				t.Fatalf("Failed to create autoscaler keda config = %v", err)
+			}
+			ctx := hpaconfig.ToContext(context.Background(), &hpaconfig.Config{
+				Autoscaler:     aConfig,
+				AutoscalerKeda: autoscalerKedaConfig})
+
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, helpers.WithAnnotations(tt.paAnnotations))
+			pa.Labels = map[string]string{serving.ServiceLabelKey: "test-service"}
+			scaledObject, err := DesiredScaledObject(ctx, pa, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DesiredScaledObject() = %v, want error: %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			triggers := scaledObject.Spec.Triggers
+			if diff := cmp.Diff(tt.wantTrigger, &triggers[len(triggers)-1]); diff != "" {
+				t.Errorf("Kafka trigger mismatch: diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithPAMetricsService("test-revision-private"))
+	pa.Labels = map[string]string{
+		serving.ServiceLabelKey:       "test-service",
+		serving.ConfigurationLabelKey: "test-config",
+		"app":                         "shop.v2",
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{{
+		name:  "revision name",
+		query: `sum(rate(http_requests_total{pod=~"{{.revisionName}}.*"}[1m]))`,
+		want:  `sum(rate(http_requests_total{pod=~"test-revision.*"}[1m]))`,
+	}, {
+		name:  "service and namespace",
+		query: `sum(rate(http_requests_total{namespace="{{.namespace}}", service="{{.serviceName}}", configuration="{{.configurationName}}"}[1m]))`,
+		want:  `sum(rate(http_requests_total{namespace="test-namespace", service="test-service", configuration="test-config"}[1m]))`,
+	}, {
+		name:  "metrics service",
+		query: `sum(rate(http_requests_total{service="{{.metricsServiceName}}"}[1m]))`,
+		want:  `sum(rate(http_requests_total{service="test-revision-private"}[1m]))`,
+	}, {
+		name:  "labels and annotations",
+		query: `sum(up{app={{index .labels "app" | quote}}, class={{index .annotations "autoscaling.knative.dev/class" | quote}}})`,
+		want:  `sum(up{app="shop.v2", class="hpa.autoscaling.knative.dev"})`,
+	}, {
+		name:  "regex escape",
+		query: `sum(up{app=~{{index .labels "app" | regexEscape | quote}}})`,
+		want:  `sum(up{app=~"shop\\.v2"})`,
+	}, {
+		name:  "default",
+		query: `sum(up{team={{index .labels "team" | default "platform" | quote}}, app={{index .labels "app" | default "none" | quote}}})`,
+		want:  `sum(up{team="platform", app="shop.v2"})`,
+	}, {
+		name:    "unknown function",
+		query:   `sum(up{app="{{.serviceName | upper}}"})`,
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderQuery(tt.query, queryValues(pa))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderQuery() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderQuery() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesiredScaledObjectEventingTriggers(t *testing.T) {
+	ctx := testContext(t, nil, nil)
+
+	kafkaSource := func(name string) *unstructured.Unstructured {
+		return eventingSource("sources.knative.dev/v1beta1", "KafkaSource", name, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"bootstrapServers": []interface{}{"kafka-0:9092"},
+				"consumerGroup":    "orders-consumer",
+				"sink":             map[string]interface{}{"ref": serviceRef("shop")},
+			},
+		})
+	}
+	cpuTrigger := kedav1alpha1.ScaleTriggers{
+		Type:       "cpu",
+		Name:       "default-trigger-cpu",
+		MetricType: autoscalingv2.UtilizationMetricType,
+		Metadata:   map[string]string{"value": "70"},
+	}
+
+	tests := []struct {
+		name          string
+		source        *unstructured.Unstructured
+		paAnnotations map[string]string
+		wantTriggers  []kedav1alpha1.ScaleTriggers
+		wantEvents    []string
+	}{{
+		// The name of the source is longer than the name of an annotation can be.
+		name:   "source with a long name",
+		source: kafkaSource("orders." + strings.Repeat("eu-west.", 8) + "consumer"),
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationEventingTriggers: "true",
+		},
+		wantTriggers: []kedav1alpha1.ScaleTriggers{cpuTrigger, {
+			Type: "kafka",
+			Name: "kafkasource-orders-" + strings.Repeat("eu-west-", 8) + "consumer",
+			Metadata: map[string]string{
+				"bootstrapServers": "kafka-0:9092",
+				"consumerGroup":    "orders-consumer",
+			},
+		}},
+	}, {
+		name:   "replaced by the revision",
+		source: kafkaSource("orders"),
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationEventingTriggers:                                           "true",
+			KedaAutoscaleAnnotationTriggerPrefix + "kafkasource-orders.type":                  "kafka",
+			KedaAutoscaleAnnotationTriggerPrefix + "kafkasource-orders.metadata.topic":        "orders",
+			KedaAutoscaleAnnotationTriggerPrefix + "kafkasource-orders.metadata.lagThreshold": "50",
+		},
+		wantTriggers: []kedav1alpha1.ScaleTriggers{cpuTrigger, {
+			Type: "kafka",
+			Name: "kafkasource-orders",
+			Metadata: map[string]string{
+				"topic":        "orders",
+				"lagThreshold": "50",
+			},
+		}},
+	}, {
+		name:   "clashing with another trigger of the revision",
+		source: kafkaSource("orders"),
+		paAnnotations: map[string]string{
+			KedaAutoscaleAnnotationEventingTriggers:        "true",
+			KedaAutoscaleAnnotationPrometheusAddress:       "http://prometheus:9090",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "kafkasource-orders", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}]`,
+		},
+		wantTriggers: []kedav1alpha1.ScaleTriggers{cpuTrigger, {
+			Type: "prometheus",
+			Name: "kafkasource-orders",
+			Metadata: map[string]string{
+				"serverAddress": "http://prometheus:9090",
+				"query":         "sum(up)",
+				"threshold":     "1",
+			},
+		}},
+		wantEvents: []string{`Warning EventingTriggerSkipped The trigger "kafkasource-orders" derived from the eventing sources is skipped, the revision already has a trigger of that name`},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			ctx := controller.WithEventRecorder(ctx, recorder)
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithReachabilityReachable, helpers.WithAnnotations(tt.paAnnotations))
+			pa.Labels = map[string]string{serving.ServiceLabelKey: "shop"}
+			scaledObject, err := DesiredScaledObject(ctx, pa, EventingTriggers(pa, []*unstructured.Unstructured{tt.source}, ""))
+			if err != nil {
+				t.Fatal("DesiredScaledObject() =", err)
+			}
+			if diff := cmp.Diff(tt.wantTriggers, scaledObject.Spec.Triggers); diff != "" {
+				t.Errorf("Triggers mismatch: diff(-want,+got):\n%s", diff)
+			}
+			close(recorder.Events)
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			if diff := cmp.Diff(tt.wantEvents, events); diff != "" {
+				t.Errorf("Events mismatch: diff(-want,+got):\n%s", diff)
+			}
+			// The derived triggers are not added to the annotations copied to the ScaledObject.
+			if errs := apivalidation.ValidateAnnotations(scaledObject.Annotations, field.NewPath("metadata", "annotations")); len(errs) > 0 {
+				t.Error("ScaledObject annotations are invalid:", errs.ToAggregate())
+			}
+		})
+	}
+}
+
+// testContext returns a context holding the autoscaler and autoscaler-keda configs
+// parsed from the given ConfigMap data.
+func testContext(t *testing.T, autoscalerData, kedaData map[string]string) context.Context {
+	t.Helper()
+	aConfig, err := config.NewConfigFromMap(autoscalerData)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler config = %v", err)
+	}
+
+	autoscalerKedaConfig, err := hpaconfig.NewConfigFromMap(kedaData)
+	if err != nil {
+		t.Fatalf("Failed to create autoscaler keda config = %v", err)
+	}
+
+	return hpaconfig.ToContext(context.Background(), &hpaconfig.Config{
+		Autoscaler:     aConfig,
+		AutoscalerKeda: autoscalerKedaConfig})
+}
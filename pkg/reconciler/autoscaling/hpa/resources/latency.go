@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+const (
+	// latencyTriggerName is the name of the latency trigger, it is referenced by the
+	// scaling modifiers formula so it must be a valid identifier.
+	latencyTriggerName = "latency"
+
+	defaultLatencyQuantile = "0.95"
+)
+
+// latencyAnnotations are the annotations the latency trigger is built from.
+var latencyAnnotations = []string{
+	KedaAutoscaleAnnotationTargetLatency,
+	KedaAutoscaleAnnotationTargetLatencyQuantile,
+	KedaAutoscaleAnnotationTargetLatencyMetric,
+}
+
+// getLatencyTrigger returns the trigger observing the latency quantile of the revision and
+// the scaling modifiers scaling it on the latency target, or nil if no target is specified.
+// The modifiers compare the observed quantile to the target as a Value metric, so that
+// the HPA grows the replicas proportionally to how far the target is exceeded.
+// The histogram metric is expected to be in seconds, as per the Prometheus conventions.
+func getLatencyTrigger(pa *autoscalingv1alpha1.PodAutoscaler, address string) (*v1alpha1.ScaleTriggers, *v1alpha1.ScalingModifiers, error) {
+	v, ok := pa.Annotations[KedaAutoscaleAnnotationTargetLatency]
+	if !ok {
+		return nil, nil, nil
+	}
+	target, err := time.ParseDuration(v)
+	if err != nil || target <= 0 {
+		return nil, nil, fmt.Errorf("invalid %s: %q, must be a positive duration", KedaAutoscaleAnnotationTargetLatency, v)
+	}
+	metric := pa.Annotations[KedaAutoscaleAnnotationTargetLatencyMetric]
+	if metric == "" {
+		return nil, nil, fmt.Errorf("%s is required with %s", KedaAutoscaleAnnotationTargetLatencyMetric, KedaAutoscaleAnnotationTargetLatency)
+	}
+	quantile := defaultLatencyQuantile
+	if v, ok := pa.Annotations[KedaAutoscaleAnnotationTargetLatencyQuantile]; ok {
+		if err := validateQuantile(KedaAutoscaleAnnotationTargetLatencyQuantile, v); err != nil {
+			return nil, nil, err
+		}
+		quantile = v
+	}
+	if _, ok := pa.Annotations[KedaAutoscaleAnnotationScalingModifiers]; ok {
+		return nil, nil, fmt.Errorf("%s cannot be combined with %s", KedaAutoscaleAnnotationTargetLatency, KedaAutoscaleAnnotationScalingModifiers)
+	}
+
+	query, err := renderQuery(quantileQuery(quantile, metric, revisionPodsMatchers, defaultQuantileRateWindow), queryValues(pa))
+	if err != nil {
+		return nil, nil, err
+	}
+	seconds := strconv.FormatFloat(target.Seconds(), 'f', -1, 64)
+	trigger := &v1alpha1.ScaleTriggers{
+		Type:       "prometheus",
+		Name:       latencyTriggerName,
+		MetricType: autoscalingv2.ValueMetricType,
+		Metadata: map[string]string{
+			"serverAddress": address,
+			"query":         query,
+			"threshold":     seconds,
+			"namespace":     pa.Namespace,
+		},
+	}
+	modifiers := &v1alpha1.ScalingModifiers{
+		Formula:    fmt.Sprintf("%s / %s", latencyTriggerName, seconds),
+		Target:     "1",
+		MetricType: autoscalingv2.ValueMetricType,
+	}
+	return trigger, modifiers, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+// NamespaceDefaultAnnotations are the annotations a namespace can set to provide defaults
+// for the revisions it contains, e.g. the Prometheus tenant and TriggerAuthentication of a team.
+var NamespaceDefaultAnnotations = []string{
+	KedaAutoscaleAnnotationPrometheusAddress,
+	KedaAutoscaleAnnotationPrometheusAuthName,
+	KedaAutoscaleAnnotationPrometheusAuthKind,
+	KedaAutoscaleAnnotationPrometheusAuthModes,
+	KedaAutoscaleAnnotationExtraPrometheusTriggers,
+	KedaAutoscaleAnnotationPollingInterval,
+	KedaAutoscaleAnnotationEventingTriggers,
+	KedaAutoscaleAnnotationEventingRabbitmqAuthName,
+	KedaAutoscaleAnnotationEventingRabbitmqAuthKind,
+}
+
+// prometheusAuthAnnotations define the authentication of the Prometheus trigger together,
+// so a revision setting any of them takes none of them from the defaults.
+var prometheusAuthAnnotations = []string{
+	KedaAutoscaleAnnotationPrometheusAuthName,
+	KedaAutoscaleAnnotationPrometheusAuthKind,
+	KedaAutoscaleAnnotationPrometheusAuthModes,
+}
+
+// NamespaceDefaults returns the NamespaceDefaultAnnotations set on the namespace.
+func NamespaceDefaults(ns *corev1.Namespace) map[string]string {
+	defaults := make(map[string]string, len(NamespaceDefaultAnnotations))
+	for _, k := range NamespaceDefaultAnnotations {
+		if v, ok := ns.Annotations[k]; ok {
+			defaults[k] = v
+		}
+	}
+	return defaults
+}
+
+// WithNamespaceDefaults returns the PA with the defaults of its namespace applied to the
+// annotations it does not set itself. The PA is copied if any default applies, so that
+// the defaults are layered between the cluster configuration and the revision annotations.
+// The Prometheus authentication is only taken from the namespace if the revision sets none
+// of its annotations.
+func WithNamespaceDefaults(pa *autoscalingv1alpha1.PodAutoscaler, ns *corev1.Namespace) *autoscalingv1alpha1.PodAutoscaler {
+	var withDefaults *autoscalingv1alpha1.PodAutoscaler
+	hasAuth := hasAnyAnnotation(pa.Annotations, prometheusAuthAnnotations)
+	for k, v := range NamespaceDefaults(ns) {
+		if _, ok := pa.Annotations[k]; ok {
+			continue
+		}
+		if hasAuth && slices.Contains(prometheusAuthAnnotations, k) {
+			continue
+		}
+		if withDefaults == nil {
+			withDefaults = pa.DeepCopy()
+			if withDefaults.Annotations == nil {
+				withDefaults.Annotations = make(map[string]string, len(NamespaceDefaultAnnotations))
+			}
+		}
+		withDefaults.Annotations[k] = v
+	}
+	if withDefaults == nil {
+		return pa
+	}
+	return withDefaults
+}
+
+// hasAnyAnnotation returns true if any of the keys is set in the annotations.
+func hasAnyAnnotation(annotations map[string]string, keys []string) bool {
+	for _, k := range keys {
+		if _, ok := annotations[k]; ok {
+			return true
+		}
+	}
+	return false
+}
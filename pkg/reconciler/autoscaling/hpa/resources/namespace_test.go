@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/serving/pkg/apis/autoscaling"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+)
+
+func TestWithNamespaceDefaults(t *testing.T) {
+	extraTriggers := `[{"name": "queue", "type": "prometheus", "metadata": {"query": "sum(queue_length)", "threshold": "10"}}]`
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: helpers.TestNamespace,
+			Annotations: map[string]string{
+				KedaAutoscaleAnnotationPrometheusAddress:       "http://thanos-tenant-a:9090",
+				KedaAutoscaleAnnotationPrometheusAuthName:      "tenant-a",
+				KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTriggers,
+				KedaAutoscaleAnnotationPollingInterval:         "1m",
+				autoscaling.MaxScaleAnnotationKey:              "3",
+			},
+		},
+	}
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, helpers.WithAnnotations(map[string]string{
+		KedaAutoscaleAnnotationPrometheusAuthName: "revision",
+	}))
+
+	got := WithNamespaceDefaults(pa, ns)
+	want := map[string]string{
+		KedaAutoscaleAnnotationPrometheusAddress:       "http://thanos-tenant-a:9090",
+		KedaAutoscaleAnnotationPrometheusAuthName:      "revision",
+		KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTriggers,
+		KedaAutoscaleAnnotationPollingInterval:         "1m",
+	}
+	if diff := cmp.Diff(want, got.Annotations); diff != "" {
+		t.Errorf("Annotations mismatch: diff(-want,+got):\n%s", diff)
+	}
+	if _, ok := pa.Annotations[KedaAutoscaleAnnotationPrometheusAddress]; ok {
+		t.Error("WithNamespaceDefaults() modified the PA")
+	}
+
+	// The authentication is taken from the namespace as a whole, or not at all.
+	ns.Annotations[KedaAutoscaleAnnotationPrometheusAuthKind] = "ClusterTriggerAuthentication"
+	ns.Annotations[KedaAutoscaleAnnotationPrometheusAuthModes] = "bearer"
+	got = WithNamespaceDefaults(pa, ns)
+	if diff := cmp.Diff(want, got.Annotations); diff != "" {
+		t.Errorf("Annotations mismatch with a revision auth name: diff(-want,+got):\n%s", diff)
+	}
+	pa = helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision)
+	got = WithNamespaceDefaults(pa, ns)
+	want = map[string]string{
+		KedaAutoscaleAnnotationPrometheusAddress:       "http://thanos-tenant-a:9090",
+		KedaAutoscaleAnnotationPrometheusAuthName:      "tenant-a",
+		KedaAutoscaleAnnotationPrometheusAuthKind:      "ClusterTriggerAuthentication",
+		KedaAutoscaleAnnotationPrometheusAuthModes:     "bearer",
+		KedaAutoscaleAnnotationExtraPrometheusTriggers: extraTriggers,
+		KedaAutoscaleAnnotationPollingInterval:         "1m",
+	}
+	if diff := cmp.Diff(want, got.Annotations); diff != "" {
+		t.Errorf("Annotations mismatch without a revision auth: diff(-want,+got):\n%s", diff)
+	}
+
+	if got := WithNamespaceDefaults(pa, &corev1.Namespace{}); got != pa {
+		t.Error("WithNamespaceDefaults() copied the PA without defaults")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"knative.dev/pkg/ptr"
+)
+
+// triggerPatchDirective is the key of a trigger patch removing the trigger when set to "delete".
+const triggerPatchDirective = "$patch"
+
+// scaledObjectPatch is the patch of the scaled-object-patch annotation, with the patches
+// of the triggers split from the rest of the patch as they are merged by name.
+type scaledObjectPatch struct {
+	patch    map[string]any
+	triggers []map[string]any
+}
+
+// getScaledObjectPatch returns the patch of the scaled-object-patch annotation, or nil if it
+// is not set.
+func getScaledObjectPatch(annotations map[string]string) (*scaledObjectPatch, error) {
+	v, ok := annotations[KedaAutoscaleAnnotationScaledObjectPatch]
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := annotations[KedaAutoscaleAnnotationsScaledObjectOverride]; ok {
+		return nil, fmt.Errorf("%s cannot be combined with %s", KedaAutoscaleAnnotationScaledObjectPatch, KedaAutoscaleAnnotationsScaledObjectOverride)
+	}
+	p := &scaledObjectPatch{}
+	if err := json.Unmarshal([]byte(v), &p.patch); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal scaled object patch: %w", err)
+	}
+	spec, _ := p.patch["spec"].(map[string]any)
+	triggers, ok := spec["triggers"]
+	if !ok {
+		return p, nil
+	}
+	delete(spec, "triggers")
+	list, ok := triggers.([]any)
+	if !ok {
+		return nil, errors.New("the triggers of the scaled object patch must be a list")
+	}
+	for _, t := range list {
+		trigger, ok := t.(map[string]any)
+		if !ok {
+			return nil, errors.New("the triggers of the scaled object patch must be objects")
+		}
+		if name, _ := trigger["name"].(string); name == "" {
+			return nil, errors.New("the triggers of the scaled object patch must have a name")
+		}
+		p.triggers = append(p.triggers, trigger)
+	}
+	return p, nil
+}
+
+// patchScaledObject applies the JSON merge patch of the scaled-object-patch annotation on top
+// of the generated ScaledObject, so that single fields can be changed without losing the
+// settings derived from the revision. Rather than replacing the whole list, each trigger of the
+// patch is merged into the generated trigger of the same name, added if there is none, or
+// removed if its "$patch" is "delete". The identity and scale target of the ScaledObject are kept.
+// Unless the patch sets them, the min replicas are derived again from the patched triggers.
+func patchScaledObject(sO *v1alpha1.ScaledObject, annotations map[string]string, minReplicas func([]v1alpha1.ScaleTriggers) int32) (*v1alpha1.ScaledObject, error) {
+	p, err := getScaledObjectPatch(annotations)
+	if err != nil || p == nil {
+		return sO, err
+	}
+
+	patched := &v1alpha1.ScaledObject{}
+	if err := mergePatch(sO, p.patch, patched); err != nil {
+		return nil, fmt.Errorf("unable to apply scaled object patch: %w", err)
+	}
+
+	for _, tp := range p.triggers {
+		name := tp["name"].(string)
+		i := 0
+		for i < len(patched.Spec.Triggers) && patched.Spec.Triggers[i].Name != name {
+			i++
+		}
+		switch {
+		case tp[triggerPatchDirective] == "delete":
+			if i == len(patched.Spec.Triggers) {
+				return nil, fmt.Errorf("unable to delete trigger %q, it does not exist", name)
+			}
+			patched.Spec.Triggers = append(patched.Spec.Triggers[:i], patched.Spec.Triggers[i+1:]...)
+		case i == len(patched.Spec.Triggers):
+			var trigger v1alpha1.ScaleTriggers
+			if err := mergePatch(struct{}{}, tp, &trigger); err != nil {
+				return nil, fmt.Errorf("unable to add trigger %q: %w", name, err)
+			}
+			patched.Spec.Triggers = append(patched.Spec.Triggers, trigger)
+		default:
+			var trigger v1alpha1.ScaleTriggers
+			if err := mergePatch(patched.Spec.Triggers[i], tp, &trigger); err != nil {
+				return nil, fmt.Errorf("unable to patch trigger %q: %w", name, err)
+			}
+			patched.Spec.Triggers[i] = trigger
+		}
+	}
+
+	patched.ObjectMeta.Name = sO.Name
+	patched.ObjectMeta.Namespace = sO.Namespace
+	patched.ObjectMeta.OwnerReferences = sO.OwnerReferences
+	patched.Spec.ScaleTargetRef = sO.Spec.ScaleTargetRef
+	spec, _ := p.patch["spec"].(map[string]any)
+	if _, ok := spec["minReplicaCount"]; !ok {
+		patched.Spec.MinReplicaCount = ptr.Int32(minReplicas(patched.Spec.Triggers))
+	}
+	if err := validatePatchedScaledObject(patched); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// validatePatchedScaledObject runs the checks of the generated ScaledObject again on the
+// patched one, as the patch can change its triggers, min replicas and fallback.
+func validatePatchedScaledObject(sO *v1alpha1.ScaledObject) error {
+	if len(sO.Spec.Triggers) == 0 {
+		return errors.New("the scaled object patch removes all the triggers")
+	}
+	// The query errors are returned as is, so that they are reported like the ones of the
+	// generated triggers.
+	if err := validatePrometheusTriggers(sO.Spec.Triggers); err != nil {
+		return err
+	}
+	if fallback := sO.Spec.Fallback; fallback != nil {
+		if fallback.FailureThreshold <= 0 {
+			return errors.New("the failure threshold of the patched fallback must be positive")
+		}
+		if err := v1alpha1.CheckFallbackValid(sO); err != nil {
+			return fmt.Errorf("invalid patched fallback: %w", err)
+		}
+	}
+	// KEDA treats a missing min replica count as zero.
+	var minReplicas int32
+	if sO.Spec.MinReplicaCount != nil {
+		minReplicas = *sO.Spec.MinReplicaCount
+	}
+	if minReplicas == 0 && !slices.ContainsFunc(sO.Spec.Triggers, func(t v1alpha1.ScaleTriggers) bool {
+		return t.Type != "cpu" && t.Type != "memory"
+	}) {
+		return errors.New("the patched min replica count must be at least 1 with only cpu and memory triggers")
+	}
+	if idle := sO.Spec.IdleReplicaCount; idle != nil {
+		if *idle != 0 {
+			return fmt.Errorf("the patched idle replica count must be 0, was: %d", *idle)
+		}
+		if minReplicas < 1 {
+			return errors.New("the patched idle replica count requires a min replica count of at least 1")
+		}
+	}
+	return nil
+}
+
+// mergePatch applies the JSON merge patch to the original and stores the result in out.
+func mergePatch(original any, patch map[string]any, out any) error {
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	merged, err := jsonpatch.MergePatch(originalJSON, patchJSON)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, out)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// QueryError reports a Prometheus trigger whose query KEDA would not be able to evaluate.
+type QueryError struct {
+	Trigger string
+	Query   string
+	Err     error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid prometheus query %q for trigger %q: %v", e.Query, e.Trigger, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// validatePrometheusTriggers checks the queries of the prometheus triggers.
+func validatePrometheusTriggers(triggers []v1alpha1.ScaleTriggers) error {
+	for _, t := range triggers {
+		if t.Type != "prometheus" {
+			continue
+		}
+		query := t.Metadata["query"]
+		if err := validatePrometheusQuery(query); err != nil {
+			return &QueryError{Trigger: t.Name, Query: query, Err: err}
+		}
+	}
+	return nil
+}
+
+// validatePrometheusQuery parses the query as PromQL and checks that it evaluates to
+// a shape KEDA can consume: a scalar or an instant vector holding a single sample.
+// Only aggregations grouped by labels are known to return several samples before
+// the query is run.
+func validatePrometheusQuery(query string) error {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return err
+	}
+	switch expr.Type() {
+	case parser.ValueTypeScalar:
+		return nil
+	case parser.ValueTypeVector:
+	default:
+		return fmt.Errorf("query must return a scalar or an instant vector, got %s", expr.Type())
+	}
+	for paren, ok := expr.(*parser.ParenExpr); ok; paren, ok = expr.(*parser.ParenExpr) {
+		expr = paren.Expr
+	}
+	if agg, ok := expr.(*parser.AggregateExpr); ok && (agg.Without || len(agg.Grouping) > 0) {
+		return fmt.Errorf("query aggregates %s labels and can return more than one sample", groupingModifier(agg))
+	}
+	return nil
+}
+
+func groupingModifier(agg *parser.AggregateExpr) string {
+	if agg.Without {
+		return "without"
+	}
+	return "by"
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+)
+
+func TestValidatePrometheusQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{{
+		name:  "aggregated vector",
+		query: `sum(rate(http_requests_total{pod=~"rev.*"}[1m]))`,
+	}, {
+		name:  "scalar",
+		query: `scalar(sum(up))`,
+	}, {
+		name:  "parenthesized aggregation",
+		query: `(avg(process_cpu_seconds_total))`,
+	}, {
+		name:  "binary expression",
+		query: `sum(rate(http_requests_total[1m])) / 2`,
+	}, {
+		name:    "syntax error",
+		query:   `sum(rate(http_requests_total[1m])`,
+		wantErr: true,
+	}, {
+		name:    "empty",
+		wantErr: true,
+	}, {
+		name:    "range vector",
+		query:   `http_requests_total[1m]`,
+		wantErr: true,
+	}, {
+		name:    "string",
+		query:   `"foo"`,
+		wantErr: true,
+	}, {
+		name:    "grouped by labels",
+		query:   `sum by (pod) (rate(http_requests_total[1m]))`,
+		wantErr: true,
+	}, {
+		name:    "grouped without labels",
+		query:   `(sum without (pod) (rate(http_requests_total[1m])))`,
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePrometheusQuery(tt.query); (err != nil) != tt.wantErr {
+				t.Errorf("validatePrometheusQuery() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePrometheusTriggers(t *testing.T) {
+	triggers := []v1alpha1.ScaleTriggers{{
+		Name:     "cpu",
+		Type:     "cpu",
+		Metadata: map[string]string{"value": "70"},
+	}, {
+		Name:     "requests",
+		Type:     "prometheus",
+		Metadata: map[string]string{"query": "sum(rate(http_requests_total[1m]))"},
+	}}
+	if err := validatePrometheusTriggers(triggers); err != nil {
+		t.Fatal("validatePrometheusTriggers() =", err)
+	}
+
+	triggers = append(triggers, v1alpha1.ScaleTriggers{
+		Name:     "broken",
+		Type:     "prometheus",
+		Metadata: map[string]string{"query": "sum(up"},
+	})
+	var queryErr *QueryError
+	if err := validatePrometheusTriggers(triggers); !errors.As(err, &queryErr) {
+		t.Fatalf("validatePrometheusTriggers() = %v, want a QueryError", err)
+	}
+	if queryErr.Trigger != "broken" || queryErr.Query != "sum(up" {
+		t.Errorf("QueryError = %+v, want trigger %q with query %q", queryErr, "broken", "sum(up")
+	}
+}
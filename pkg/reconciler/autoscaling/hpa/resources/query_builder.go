@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	defaultQueryAggregation = "sum"
+	// defaultQuantileRateWindow is the window the rate of the histogram buckets is
+	// computed over when no rate window is specified.
+	defaultQuantileRateWindow = "1m"
+
+	// revisionPodsMatchers scope the built queries to the pods of the revision.
+	revisionPodsMatchers = `namespace="{{ .namespace }}", pod=~"{{ .revisionName }}-deployment-.*"`
+)
+
+// queryBuilderAnnotations are the annotations the query is built from.
+var queryBuilderAnnotations = []string{
+	KedaAutoscaleAnnotationPrometheusQueryMetric,
+	KedaAutoscaleAnnotationPrometheusQueryLabels,
+	KedaAutoscaleAnnotationPrometheusQueryAggregation,
+	KedaAutoscaleAnnotationPrometheusQueryRateWindow,
+	KedaAutoscaleAnnotationPrometheusQueryQuantile,
+}
+
+// queryAggregations are the aggregations the built queries can apply across the pods.
+var queryAggregations = map[string]bool{
+	"sum": true,
+	"avg": true,
+	"min": true,
+	"max": true,
+}
+
+// buildQuery returns the query template built from the query builder annotations, or
+// false if no metric is specified. The query aggregates the metric of the revision's pods,
+// optionally as a rate, or computes a quantile from the buckets of a histogram metric.
+func buildQuery(annotations map[string]string) (string, bool, error) {
+	metric, ok := annotations[KedaAutoscaleAnnotationPrometheusQueryMetric]
+	if !ok {
+		return "", false, nil
+	}
+	if metric == "" {
+		return "", false, fmt.Errorf("%s must not be empty", KedaAutoscaleAnnotationPrometheusQueryMetric)
+	}
+
+	matchers := revisionPodsMatchers
+	if v := strings.TrimSpace(annotations[KedaAutoscaleAnnotationPrometheusQueryLabels]); v != "" {
+		matchers += ", " + v
+	}
+
+	window, hasWindow := annotations[KedaAutoscaleAnnotationPrometheusQueryRateWindow]
+	if hasWindow {
+		if _, err := model.ParseDuration(window); err != nil {
+			return "", false, fmt.Errorf("invalid %s: %w", KedaAutoscaleAnnotationPrometheusQueryRateWindow, err)
+		}
+	}
+
+	aggregation, hasAggregation := annotations[KedaAutoscaleAnnotationPrometheusQueryAggregation]
+	if !hasAggregation {
+		aggregation = defaultQueryAggregation
+	} else if !queryAggregations[aggregation] {
+		return "", false, fmt.Errorf("invalid %s: %q, must be one of sum, avg, min or max", KedaAutoscaleAnnotationPrometheusQueryAggregation, aggregation)
+	}
+
+	if v, ok := annotations[KedaAutoscaleAnnotationPrometheusQueryQuantile]; ok {
+		if err := validateQuantile(KedaAutoscaleAnnotationPrometheusQueryQuantile, v); err != nil {
+			return "", false, err
+		}
+		if hasAggregation {
+			return "", false, fmt.Errorf("%s cannot be combined with %s", KedaAutoscaleAnnotationPrometheusQueryAggregation, KedaAutoscaleAnnotationPrometheusQueryQuantile)
+		}
+		if !hasWindow {
+			window = defaultQuantileRateWindow
+		}
+		return quantileQuery(v, metric, matchers, window), true, nil
+	}
+
+	selector := fmt.Sprintf("%s{%s}", metric, matchers)
+	if hasWindow {
+		selector = fmt.Sprintf("rate(%s[%s])", selector, window)
+	}
+	return fmt.Sprintf("%s(%s)", aggregation, selector), true, nil
+}
+
+// quantileQuery returns the query computing the quantile of the histogram metric from
+// the rate of its buckets over the window.
+func quantileQuery(quantile, metric, matchers, window string) string {
+	return fmt.Sprintf("histogram_quantile(%s, sum by (le) (rate(%s_bucket{%s}[%s])))", quantile, metric, matchers, window)
+}
+
+// validateQuantile returns an error if the value of the annotation key is not a
+// quantile strictly between 0 and 1.
+func validateQuantile(key, v string) error {
+	if q, err := strconv.ParseFloat(v, 64); err != nil || q <= 0 || q >= 1 {
+		return fmt.Errorf("invalid %s: %q, must be a number between 0 and 1 exclusive", key, v)
+	}
+	return nil
+}
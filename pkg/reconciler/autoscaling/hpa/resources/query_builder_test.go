@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+)
+
+func TestBuildQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantBuilt   bool
+		wantErr     bool
+	}{{
+		name: "no metric",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryRateWindow: "1m",
+		},
+	}, {
+		name: "gauge",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric: "jobs_in_progress",
+		},
+		want:      `sum(jobs_in_progress{namespace="{{ .namespace }}", pod=~"{{ .revisionName }}-deployment-.*"})`,
+		wantBuilt: true,
+	}, {
+		name: "rate with labels and aggregation",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:      "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryLabels:      `code=~"2..", method="GET"`,
+			KedaAutoscaleAnnotationPrometheusQueryAggregation: "avg",
+			KedaAutoscaleAnnotationPrometheusQueryRateWindow:  "2m",
+		},
+		want:      `avg(rate(http_requests_total{namespace="{{ .namespace }}", pod=~"{{ .revisionName }}-deployment-.*", code=~"2..", method="GET"}[2m]))`,
+		wantBuilt: true,
+	}, {
+		name: "quantile",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:   "http_request_duration_seconds",
+			KedaAutoscaleAnnotationPrometheusQueryQuantile: "0.95",
+		},
+		want:      `histogram_quantile(0.95, sum by (le) (rate(http_request_duration_seconds_bucket{namespace="{{ .namespace }}", pod=~"{{ .revisionName }}-deployment-.*"}[1m])))`,
+		wantBuilt: true,
+	}, {
+		name: "empty metric",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric: "",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid rate window",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:     "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryRateWindow: "a minute",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid aggregation",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:      "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryAggregation: "count",
+		},
+		wantErr: true,
+	}, {
+		name: "quantile out of range",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:   "http_request_duration_seconds",
+			KedaAutoscaleAnnotationPrometheusQueryQuantile: "95",
+		},
+		wantErr: true,
+	}, {
+		name: "quantile of one",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:   "http_request_duration_seconds",
+			KedaAutoscaleAnnotationPrometheusQueryQuantile: "1",
+		},
+		wantErr: true,
+	}, {
+		name: "quantile with aggregation",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPrometheusQueryMetric:      "http_request_duration_seconds",
+			KedaAutoscaleAnnotationPrometheusQueryQuantile:    "0.95",
+			KedaAutoscaleAnnotationPrometheusQueryAggregation: "max",
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, built, err := buildQuery(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildQuery() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || built != tt.wantBuilt {
+				t.Errorf("buildQuery() = %s, %v, want %s, %v", got, built, tt.want, tt.wantBuilt)
+			}
+		})
+	}
+}
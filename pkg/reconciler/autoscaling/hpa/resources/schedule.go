@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// scheduleTriggerName is the name of the trigger of the schedule annotations, and the
+	// prefix of the names of the triggers of the schedules annotation.
+	scheduleTriggerName = "schedule"
+
+	defaultScheduleTimezone = "Etc/UTC"
+)
+
+// scheduleAnnotations are the annotations the schedule triggers are built from.
+var scheduleAnnotations = []string{
+	KedaAutoscaleAnnotationScheduleStart,
+	KedaAutoscaleAnnotationScheduleEnd,
+	KedaAutoscaleAnnotationScheduleDesiredReplicas,
+	KedaAutoscaleAnnotationScheduleTimezone,
+	KedaAutoscaleAnnotationSchedules,
+}
+
+// cronParser parses the cron expressions like the KEDA cron scaler does.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// scheduleWindow is a window of a revision schedule, the revision is scaled to at least
+// the desired replicas from its start to its end.
+type scheduleWindow struct {
+	Name            string `json:"name"`
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	DesiredReplicas int32  `json:"desiredReplicas"`
+	Timezone        string `json:"timezone,omitempty"`
+}
+
+// getScheduleTriggers returns the cron triggers of the schedule windows of the revision,
+// which are combined with its other triggers so that the windows provide a floor to the
+// replicas. The windows are specified either by the start, end and desired replicas
+// annotations, or as a list in json format for several windows.
+func getScheduleTriggers(annotations map[string]string) ([]v1alpha1.ScaleTriggers, error) {
+	timezone := defaultScheduleTimezone
+	if v, ok := annotations[KedaAutoscaleAnnotationScheduleTimezone]; ok {
+		timezone = v
+	}
+
+	var windows []scheduleWindow
+	_, hasStart := annotations[KedaAutoscaleAnnotationScheduleStart]
+	_, hasEnd := annotations[KedaAutoscaleAnnotationScheduleEnd]
+	_, hasReplicas := annotations[KedaAutoscaleAnnotationScheduleDesiredReplicas]
+	if hasStart || hasEnd || hasReplicas {
+		if !hasStart || !hasEnd || !hasReplicas {
+			return nil, fmt.Errorf("%s, %s and %s must be specified together", KedaAutoscaleAnnotationScheduleStart,
+				KedaAutoscaleAnnotationScheduleEnd, KedaAutoscaleAnnotationScheduleDesiredReplicas)
+		}
+		v := annotations[KedaAutoscaleAnnotationScheduleDesiredReplicas]
+		replicas, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q, must be a whole number", KedaAutoscaleAnnotationScheduleDesiredReplicas, v)
+		}
+		windows = append(windows, scheduleWindow{
+			Name:            scheduleTriggerName,
+			Start:           annotations[KedaAutoscaleAnnotationScheduleStart],
+			End:             annotations[KedaAutoscaleAnnotationScheduleEnd],
+			DesiredReplicas: int32(replicas),
+		})
+	}
+	if v, ok := annotations[KedaAutoscaleAnnotationSchedules]; ok {
+		var list []scheduleWindow
+		if err := json.Unmarshal([]byte(v), &list); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal %s: %w", KedaAutoscaleAnnotationSchedules, err)
+		}
+		names := make(map[string]bool, len(list))
+		for _, w := range list {
+			if w.Name == "" {
+				return nil, fmt.Errorf("the windows of %s must have a name", KedaAutoscaleAnnotationSchedules)
+			}
+			if names[w.Name] {
+				return nil, fmt.Errorf("window %q of %s is defined more than once", w.Name, KedaAutoscaleAnnotationSchedules)
+			}
+			names[w.Name] = true
+			w.Name = scheduleTriggerName + "-" + w.Name
+			windows = append(windows, w)
+		}
+	}
+
+	triggers := make([]v1alpha1.ScaleTriggers, 0, len(windows))
+	for _, w := range windows {
+		if w.Timezone == "" {
+			w.Timezone = timezone
+		}
+		if err := validateScheduleWindow(w); err != nil {
+			return nil, fmt.Errorf("invalid schedule window %q: %w", w.Name, err)
+		}
+		triggers = append(triggers, v1alpha1.ScaleTriggers{
+			Type: cronTriggerType,
+			Name: w.Name,
+			Metadata: map[string]string{
+				"timezone":        w.Timezone,
+				"start":           w.Start,
+				"end":             w.End,
+				"desiredReplicas": strconv.Itoa(int(w.DesiredReplicas)),
+			},
+		})
+	}
+	return triggers, nil
+}
+
+// validateScheduleWindow checks the window like the KEDA cron scaler does, so that an
+// invalid window is rejected before KEDA fails to build the scaler.
+func validateScheduleWindow(w scheduleWindow) error {
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", w.Timezone, err)
+	}
+	if _, err := cronParser.Parse(w.Start); err != nil {
+		return fmt.Errorf("invalid start %q: %w", w.Start, err)
+	}
+	if _, err := cronParser.Parse(w.End); err != nil {
+		return fmt.Errorf("invalid end %q: %w", w.End, err)
+	}
+	if w.Start == w.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	if w.DesiredReplicas <= 0 {
+		return fmt.Errorf("desired replicas must be positive, got %d", w.DesiredReplicas)
+	}
+	return nil
+}
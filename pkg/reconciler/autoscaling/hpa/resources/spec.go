@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"knative.dev/pkg/ptr"
+)
+
+// specAnnotation is an annotation setting a field of the ScaledObject spec.
+type specAnnotation struct {
+	key string
+	set func(spec *v1alpha1.ScaledObjectSpec, v string) error
+}
+
+// specAnnotations are the annotations setting the ScaledObject spec fields that are not
+// derived from the Knative autoscaling annotations.
+var specAnnotations = []specAnnotation{{
+	key: KedaAutoscaleAnnotationPollingInterval,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		seconds, err := parseSeconds(v, time.Second)
+		spec.PollingInterval = seconds
+		return err
+	},
+}, {
+	key: KedaAutoscaleAnnotationCooldownPeriod,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		seconds, err := parseSeconds(v, 0)
+		spec.CooldownPeriod = seconds
+		return err
+	},
+}, {
+	key: KedaAutoscaleAnnotationInitialCooldownPeriod,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		seconds, err := parseSeconds(v, 0)
+		if err != nil {
+			return err
+		}
+		spec.InitialCooldownPeriod = *seconds
+		return nil
+	},
+}, {
+	key: KedaAutoscaleAnnotationIdleReplicaCount,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		replicas, err := parseReplicas(v)
+		if err != nil {
+			return err
+		}
+		// KEDA only supports scaling idle targets to zero.
+		if *replicas != 0 {
+			return errors.New("must be 0")
+		}
+		spec.IdleReplicaCount = replicas
+		return nil
+	},
+}, {
+	key: KedaAutoscaleAnnotationFallbackFailureThreshold,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		threshold, err := parseReplicas(v)
+		if err != nil {
+			return err
+		}
+		if spec.Fallback == nil {
+			spec.Fallback = &v1alpha1.Fallback{}
+		}
+		spec.Fallback.FailureThreshold = *threshold
+		return nil
+	},
+}, {
+	key: KedaAutoscaleAnnotationFallbackReplicas,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		replicas, err := parseReplicas(v)
+		if err != nil {
+			return err
+		}
+		if spec.Fallback == nil {
+			spec.Fallback = &v1alpha1.Fallback{}
+		}
+		spec.Fallback.Replicas = *replicas
+		return nil
+	},
+}, {
+	key: KedaAutoscaleAnnotationRestoreToOriginalReplicaCount,
+	set: func(spec *v1alpha1.ScaledObjectSpec, v string) error {
+		restore, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		if spec.Advanced == nil {
+			spec.Advanced = &v1alpha1.AdvancedConfig{}
+		}
+		spec.Advanced.RestoreToOriginalReplicaCount = restore
+		return nil
+	},
+}}
+
+// setSpecAnnotations sets the ScaledObject spec fields specified by the annotations, on
+// top of the generated spec and the defaults of the config-autoscaler-keda ConfigMap.
+// The spec must hold its triggers and min replicas, the annotations are checked against them.
+func setSpecAnnotations(spec *v1alpha1.ScaledObjectSpec, annotations map[string]string) error {
+	// The fallback of the revision replaces the default one as a whole.
+	defaultFallback := spec.Fallback
+	spec.Fallback = nil
+	for _, a := range specAnnotations {
+		v, ok := annotations[a.key]
+		if !ok {
+			continue
+		}
+		if err := a.set(spec, v); err != nil {
+			return fmt.Errorf("invalid %s: %q: %w", a.key, v, err)
+		}
+	}
+
+	if spec.Fallback == nil {
+		spec.Fallback = defaultFallback
+	} else if err := validateFallback(spec); err != nil {
+		return err
+	} else if err := v1alpha1.CheckFallbackValid(&v1alpha1.ScaledObject{Spec: *spec}); err != nil {
+		return fmt.Errorf("invalid fallback: %w", err)
+	}
+
+	if spec.IdleReplicaCount != nil && (spec.MinReplicaCount == nil || *spec.MinReplicaCount < 1) {
+		return fmt.Errorf("%s requires a min scale of at least 1", KedaAutoscaleAnnotationIdleReplicaCount)
+	}
+	return nil
+}
+
+// validateFallback checks that a fallback set by the annotations has a failure threshold.
+func validateFallback(spec *v1alpha1.ScaledObjectSpec) error {
+	if spec.Fallback != nil && spec.Fallback.FailureThreshold == 0 {
+		return fmt.Errorf("%s must be positive to enable the fallback", KedaAutoscaleAnnotationFallbackFailureThreshold)
+	}
+	return nil
+}
+
+// parseSeconds parses the duration as a whole number of seconds, of at least the given minimum.
+func parseSeconds(v string, least time.Duration) (*int32, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return nil, err
+	}
+	if d < least || d%time.Second != 0 {
+		return nil, fmt.Errorf("must be a whole number of seconds of at least %v, e.g. 30s", least)
+	}
+	return ptr.Int32(int32(d.Seconds())), nil
+}
+
+// parseReplicas parses a non-negative number of replicas.
+func parseReplicas(v string) (*int32, error) {
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.New("must not be negative")
+	}
+	return ptr.Int32(int32(n)), nil
+}
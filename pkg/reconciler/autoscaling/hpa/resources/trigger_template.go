@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+
+	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
+)
+
+// getTriggerTemplateParams returns the parameters the trigger template is rendered with.
+func getTriggerTemplateParams(annotations map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+	if v, ok := annotations[KedaAutoscaleAnnotationTriggerTemplateParams]; ok {
+		if err := json.Unmarshal([]byte(v), &params); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal trigger template params: %w", err)
+		}
+	}
+	return params, nil
+}
+
+// getTemplateTrigger returns the trigger rendered from the trigger template the PA
+// references, or nil if it references none. The metadata of the template is rendered
+// like the extra triggers, with the parameters of the PA available as `params`.
+func getTemplateTrigger(pa *autoscalingv1alpha1.PodAutoscaler, templates map[string]v1alpha1.ScaleTriggers, address string) (*v1alpha1.ScaleTriggers, error) {
+	name, ok := pa.Annotations[KedaAutoscaleAnnotationTriggerTemplate]
+	if !ok {
+		return nil, nil
+	}
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("trigger template %q is not defined in %s", name, hpaconfig.AutoscalerKedaConfigName)
+	}
+	params, err := getTriggerTemplateParams(pa.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	values := queryValues(pa)
+	values["params"] = params
+	// The templates are shared by all the revisions, so they are rendered on a copy.
+	triggers := []v1alpha1.ScaleTriggers{*tmpl.DeepCopy()}
+	if err := renderExtraPrometheusTriggers(triggers, values, address); err != nil {
+		return nil, err
+	}
+	return &triggers[0], nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/apis"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+
+	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+)
+
+// ValidateAnnotations runs the parsing DesiredScaledObject applies to the KEDA
+// autoscaling annotations of a revision, so that invalid values can be rejected
+// before a ScaledObject is built from them. The returned errors point to the
+// offending annotation keys.
+func ValidateAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	// The PA of a revision carries the revision annotations.
+	pa := &autoscalingv1alpha1.PodAutoscaler{}
+	pa.Annotations = annotations
+
+	if _, err := getScaledObjectOverride(annotations); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationsScaledObjectOverride, err))
+	}
+	if _, err := getScaledObjectPatch(annotations); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationScaledObjectPatch, err))
+	}
+	if _, err := getScalingModifiers(annotations); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationScalingModifiers, err))
+	}
+	for _, key := range []string{KedaAutoscalingAnnotationHPAScaleUpRules, KedaAutoscalingAnnotationHPAScaleDownRules} {
+		if _, err := getScalingRules(annotations, key); err != nil {
+			errs = errs.Also(invalidAnnotation(annotations, key, err))
+		}
+	}
+	if triggers, err := getExtraPrometheusTriggers(annotations); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationExtraPrometheusTriggers, err))
+	} else if err := renderExtraPrometheusTriggers(triggers, queryValues(placeholderRevision(annotations)), hpaconfig.DefaultPrometheusAddress); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationExtraPrometheusTriggers, err))
+	} else if err := validatePrometheusTriggers(triggers); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationExtraPrometheusTriggers, err))
+	}
+	if v, ok := annotations[KedaAutoscaleAnnotationTriggerTemplate]; ok && v == "" {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationTriggerTemplate, fmt.Errorf("the name of the trigger template must not be empty")))
+	}
+	if _, err := getTriggerTemplateParams(annotations); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationTriggerTemplateParams, err))
+	}
+	var spec v1alpha1.ScaledObjectSpec
+	for _, a := range specAnnotations {
+		if v, ok := annotations[a.key]; ok {
+			if err := a.set(&spec, v); err != nil {
+				errs = errs.Also(invalidAnnotation(annotations, a.key, err))
+			}
+		}
+	}
+	if err := validateFallback(&spec); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), KedaAutoscaleAnnotationFallbackFailureThreshold, KedaAutoscaleAnnotationFallbackReplicas))
+	}
+	if triggers, fieldErr := getAnnotationTriggers(annotations); fieldErr != nil {
+		errs = errs.Also(fieldErr)
+	} else if err := renderExtraPrometheusTriggers(triggers, queryValues(placeholderRevision(annotations)), hpaconfig.DefaultPrometheusAddress); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), KedaAutoscaleAnnotationTriggerPrefix+"*"))
+	} else if err := validatePrometheusTriggers(triggers); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), KedaAutoscaleAnnotationTriggerPrefix+"*"))
+	}
+	// The bootstrap servers may come from the ConfigMap, which is not known here.
+	if _, err := getKafkaTrigger(placeholderRevision(annotations), "bootstrap:9092"); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), kafkaAnnotations...))
+	}
+	if err := validateEventingRabbitmqAuth(annotations); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), KedaAutoscaleAnnotationEventingRabbitmqAuthName, KedaAutoscaleAnnotationEventingRabbitmqAuthKind))
+	}
+	if _, err := getScheduleTriggers(annotations); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), scheduleAnnotations...))
+	}
+	errs = errs.Also(validateTriggerNames(annotations))
+	if _, err := getMetricType(annotations, pa.Metric()); err != nil {
+		errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationMetricType, err))
+	}
+	for _, key := range []string{KedaAutoscaleAnnotationEventingTriggers, KedaAutoscaleAnnotationActivateFromZero} {
+		if v, ok := annotations[key]; ok {
+			if _, err := strconv.ParseBool(v); err != nil {
+				errs = errs.Also(invalidAnnotation(annotations, key, err))
+			}
+		}
+	}
+	if trigger, _, err := getLatencyTrigger(placeholderRevision(annotations), hpaconfig.DefaultPrometheusAddress); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), latencyAnnotations...))
+	} else if trigger != nil {
+		if err := validatePrometheusQuery(trigger.Metadata["query"]); err != nil {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("invalid latency query: %v", err), latencyAnnotations...))
+		}
+	}
+
+	switch pa.Metric() {
+	case autoscaling.CPU, autoscaling.Memory:
+		return errs
+	}
+	if _, ok := pa.Target(); ok && !isBuiltinMetric(pa.Metric()) {
+		_, hasQuery := annotations[KedaAutoscaleAnnotationPrometheusQuery]
+		_, hasQueryMetric := annotations[KedaAutoscaleAnnotationPrometheusQueryMetric]
+		if !hasQuery && !hasQueryMetric {
+			errs = errs.Also(apis.ErrMissingField(KedaAutoscaleAnnotationPrometheusQuery))
+		}
+	}
+	if query, ok, err := buildQuery(annotations); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), queryBuilderAnnotations...))
+	} else if ok {
+		if query, err = renderQuery(query, queryValues(placeholderRevision(annotations))); err == nil {
+			err = validatePrometheusQuery(query)
+		}
+		if err != nil {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("invalid built query: %v", err), queryBuilderAnnotations...))
+		}
+	}
+	if v, ok := annotations[KedaAutoscaleAnnotationPrometheusQuery]; ok {
+		// The query is rendered for a placeholder revision, as the revision
+		// is not known yet when a Service or Configuration is validated.
+		if query, err := renderQuery(v, queryValues(placeholderRevision(annotations))); err != nil {
+			errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationPrometheusQuery, err))
+		} else if err := validatePrometheusQuery(query); err != nil {
+			errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationPrometheusQuery, err))
+		}
+	}
+	if v, ok := annotations[KedaAutoscaleAnnotationPrometheusAddress]; ok {
+		if err := helpers.ParseServerAddress(v); err != nil {
+			errs = errs.Also(invalidAnnotation(annotations, KedaAutoscaleAnnotationPrometheusAddress, err))
+		}
+	}
+	if _, err := getDefaultPrometheusTrigger(annotations, nil, "", "", "", "", ""); err != nil {
+		errs = errs.Also(apis.ErrGeneric(err.Error(), KedaAutoscaleAnnotationPrometheusAuthName,
+			KedaAutoscaleAnnotationPrometheusAuthKind, KedaAutoscaleAnnotationPrometheusAuthModes))
+	}
+	return errs
+}
+
+// validateTriggerNames checks that the triggers defined by the annotations have distinct
+// names, as KEDA rejects the ScaledObjects whose triggers share a name. Invalid triggers
+// are reported by the other checks. The trigger templates are only known to the
+// controller, which checks their names when building the ScaledObject.
+func validateTriggerNames(annotations map[string]string) *apis.FieldError {
+	// keys holds the annotation defining each trigger, by trigger name.
+	keys := map[string][]string{}
+	add := func(key string, triggers ...v1alpha1.ScaleTriggers) {
+		for _, t := range triggers {
+			if t.Name != "" {
+				keys[t.Name] = append(keys[t.Name], key)
+			}
+		}
+	}
+
+	// Revisions without a metric annotation scale on cpu.
+	switch metric, ok := annotations[autoscaling.MetricAnnotationKey]; {
+	case !ok, metric == autoscaling.CPU:
+		add(autoscaling.MetricAnnotationKey, v1alpha1.ScaleTriggers{Name: defaultCPUTriggerName})
+	case metric == autoscaling.Memory:
+		add(autoscaling.MetricAnnotationKey, v1alpha1.ScaleTriggers{Name: defaultMemoryTriggerName})
+	default:
+		if name, ok := annotations[KedaAutoscalerAnnnotationPrometheusName]; ok {
+			add(KedaAutoscalerAnnnotationPrometheusName, v1alpha1.ScaleTriggers{Name: name})
+		} else {
+			add(autoscaling.MetricAnnotationKey, v1alpha1.ScaleTriggers{Name: defaultCustomTriggerName})
+		}
+	}
+	if triggers, err := getExtraPrometheusTriggers(annotations); err == nil {
+		add(KedaAutoscaleAnnotationExtraPrometheusTriggers, triggers...)
+	}
+	if trigger, _, err := getLatencyTrigger(placeholderRevision(annotations), hpaconfig.DefaultPrometheusAddress); err == nil && trigger != nil {
+		add(KedaAutoscaleAnnotationTargetLatency, *trigger)
+	}
+	// The invalid fields of the triggers are reported by ValidateAnnotations.
+	triggers, _ := getAnnotationTriggers(annotations)
+	add(KedaAutoscaleAnnotationTriggerPrefix+"*", triggers...)
+	if trigger, err := getKafkaTrigger(placeholderRevision(annotations), "bootstrap:9092"); err == nil && trigger != nil {
+		add(KedaAutoscaleAnnotationKafkaTopic, *trigger)
+	}
+	if triggers, err := getScheduleTriggers(annotations); err == nil {
+		for _, t := range triggers {
+			if t.Name == scheduleTriggerName {
+				add(KedaAutoscaleAnnotationScheduleStart, t)
+			} else {
+				add(KedaAutoscaleAnnotationSchedules, t)
+			}
+		}
+	}
+
+	var errs *apis.FieldError
+	for _, name := range sets.List(sets.KeySet(keys)) {
+		if len(keys[name]) > 1 {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("trigger %q is defined more than once", name), sets.List(sets.New(keys[name]...))...))
+		}
+	}
+	return errs
+}
+
+func invalidAnnotation(annotations map[string]string, key string, err error) *apis.FieldError {
+	return apis.ErrInvalidValue(annotations[key], key, err.Error())
+}
+
+// placeholderRevision returns a PA standing for a revision with the given annotations
+// in the query template.
+func placeholderRevision(annotations map[string]string) *autoscalingv1alpha1.PodAutoscaler {
+	pa := &autoscalingv1alpha1.PodAutoscaler{}
+	pa.Name = "revision"
+	pa.Namespace = "namespace"
+	pa.Labels = map[string]string{
+		serving.ServiceLabelKey:       "service",
+		serving.ConfigurationLabelKey: "configuration",
+	}
+	pa.Annotations = annotations
+	pa.Status.MetricsServiceName = "revision-private"
+	return pa
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantPaths   []string
+	}{{
+		name: "cpu defaults",
+	}, {
+		name: "valid custom metric",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:            "http_requests_total",
+			autoscaling.TargetAnnotationKey:            "5",
+			KedaAutoscaleAnnotationPrometheusQuery:     `sum(rate(http_requests_total{pod=~"{{.revisionName}}.*"}[1m]))`,
+			KedaAutoscaleAnnotationPrometheusAddress:   "http://prometheus.monitoring.svc:9090",
+			KedaAutoscaleAnnotationScalingModifiers:    `{"formula": "(trigger1 + trigger2)/2", "target": "5"}`,
+			KedaAutoscalingAnnotationHPAScaleUpRules:   `{"stabilizationWindowSeconds": 10}`,
+			KedaAutoscaleAnnotationPrometheusAuthName:  "keda-trigger-auth-prometheus",
+			KedaAutoscaleAnnotationPrometheusAuthModes: "bearer",
+		},
+	}, {
+		name: "built-in metric without query",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey: autoscaling.RPS,
+			autoscaling.TargetAnnotationKey: "100",
+		},
+	}, {
+		name: "built query",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "http_requests_total",
+			autoscaling.TargetAnnotationKey:                "5",
+			KedaAutoscaleAnnotationPrometheusQueryMetric:   "http_request_duration_seconds",
+			KedaAutoscaleAnnotationPrometheusQueryQuantile: "0.9",
+		},
+	}, {
+		name: "invalid built query",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:              "http_requests_total",
+			autoscaling.TargetAnnotationKey:              "5",
+			KedaAutoscaleAnnotationPrometheusQueryMetric: "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusQueryLabels: `code="200`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusQueryLabels},
+	}, {
+		name: "target latency",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTargetLatency:         "300ms",
+			KedaAutoscaleAnnotationTargetLatencyQuantile: "0.99",
+			KedaAutoscaleAnnotationTargetLatencyMetric:   "http_request_duration_seconds",
+		},
+	}, {
+		name: "invalid target latency",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTargetLatency:         "300",
+			KedaAutoscaleAnnotationTargetLatencyQuantile: "99",
+			KedaAutoscaleAnnotationTargetLatencyMetric:   "http_request_duration_seconds",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationTargetLatency},
+	}, {
+		name: "target latency with scaling modifiers",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTargetLatency:       "300ms",
+			KedaAutoscaleAnnotationTargetLatencyMetric: "http_request_duration_seconds",
+			KedaAutoscaleAnnotationScalingModifiers:    `{"formula": "latency", "target": "1"}`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationScalingModifiers},
+	}, {
+		name: "trigger template",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:       "http-rate",
+			KedaAutoscaleAnnotationTriggerTemplateParams: `{"metric": "http_requests_total"}`,
+		},
+	}, {
+		name: "invalid trigger template",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerTemplate:       "",
+			KedaAutoscaleAnnotationTriggerTemplateParams: `{"metric": ["http_requests_total"]}`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationTriggerTemplate, KedaAutoscaleAnnotationTriggerTemplateParams},
+	}, {
+		name: "annotation trigger",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":               "rabbitmq",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.metadata.queueName": "{{ .revisionName }}",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.auth-name":          "rabbitmq-auth",
+		},
+	}, {
+		name: "invalid annotation triggers",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type":            "rabbit",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.metadata.query": "sum(up)",
+			KedaAutoscaleAnnotationTriggerPrefix + "lag.type":              "kafka",
+			KedaAutoscaleAnnotationTriggerPrefix + "lag.auth-kind":         "Secret",
+		},
+		wantPaths: []string{
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.type",
+			KedaAutoscaleAnnotationTriggerPrefix + "lag.auth-kind",
+			KedaAutoscaleAnnotationTriggerPrefix + "lag.auth-name",
+		},
+	}, {
+		name: "invalid annotation trigger query",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.type":           "prometheus",
+			KedaAutoscaleAnnotationTriggerPrefix + "errors.metadata.query": "sum by (pod) (up)",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationTriggerPrefix + "*"},
+	}, {
+		name: "annotation trigger named like the prometheus trigger",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                     "http_requests_total",
+			KedaAutoscalerAnnnotationPrometheusName:             "queue",
+			KedaAutoscaleAnnotationTriggerPrefix + "queue.type": "rabbitmq",
+		},
+		wantPaths: []string{KedaAutoscalerAnnnotationPrometheusName, KedaAutoscaleAnnotationTriggerPrefix + "*"},
+	}, {
+		name: "schedule",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationScheduleStart:           "0 8 * * 1-5",
+			KedaAutoscaleAnnotationScheduleEnd:             "0 18 * * 1-5",
+			KedaAutoscaleAnnotationScheduleDesiredReplicas: "5",
+			KedaAutoscaleAnnotationScheduleTimezone:        "Europe/Berlin",
+		},
+	}, {
+		name: "invalid schedules",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationSchedules: `[{"name": "morning", "start": "8am", "end": "0 12 * * *", "desiredReplicas": 10}]`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationSchedules},
+	}, {
+		name: "schedule window named like an extra trigger",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationSchedules:               `[{"name": "morning", "start": "0 8 * * *", "end": "0 12 * * *", "desiredReplicas": 10}]`,
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "schedule-morning", "type": "prometheus", "metadata": {"query": "sum(up)", "threshold": "1"}}]`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationExtraPrometheusTriggers, KedaAutoscaleAnnotationSchedules},
+	}, {
+		name: "kafka consumer lag",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaTopic:         "orders",
+			KedaAutoscaleAnnotationKafkaConsumerGroup: "{{ .serviceName }}",
+		},
+	}, {
+		name: "invalid kafka consumer lag",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationKafkaConsumerGroup: "{{ .serviceName }}",
+			KedaAutoscaleAnnotationKafkaLagThreshold:  "lots",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationKafkaLagThreshold},
+	}, {
+		name: "kafka trigger named like the prometheus trigger",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:           "http_requests_total",
+			KedaAutoscalerAnnnotationPrometheusName:   "kafka",
+			KedaAutoscaleAnnotationKafkaConsumerGroup: "orders",
+		},
+		wantPaths: []string{KedaAutoscalerAnnnotationPrometheusName, KedaAutoscaleAnnotationKafkaTopic},
+	}, {
+		name: "invalid eventing triggers",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationEventingTriggers: "yes",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationEventingTriggers},
+	}, {
+		name: "invalid polling interval",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationPollingInterval: "1.5s",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPollingInterval},
+	}, {
+		name: "spec annotations",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationCooldownPeriod:                "5m",
+			KedaAutoscaleAnnotationInitialCooldownPeriod:         "0s",
+			KedaAutoscaleAnnotationIdleReplicaCount:              "0",
+			KedaAutoscaleAnnotationFallbackFailureThreshold:      "3",
+			KedaAutoscaleAnnotationFallbackReplicas:              "1",
+			KedaAutoscaleAnnotationRestoreToOriginalReplicaCount: "false",
+		},
+	}, {
+		name: "idle replica count not zero",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationIdleReplicaCount: "1",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationIdleReplicaCount},
+	}, {
+		name: "invalid spec annotations",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationCooldownPeriod:                "-5m",
+			KedaAutoscaleAnnotationIdleReplicaCount:              "none",
+			KedaAutoscaleAnnotationFallbackReplicas:              "1",
+			KedaAutoscaleAnnotationRestoreToOriginalReplicaCount: "maybe",
+		},
+		wantPaths: []string{
+			KedaAutoscaleAnnotationCooldownPeriod,
+			KedaAutoscaleAnnotationIdleReplicaCount,
+			KedaAutoscaleAnnotationFallbackFailureThreshold,
+			KedaAutoscaleAnnotationRestoreToOriginalReplicaCount,
+		},
+	}, {
+		name: "scaled object patch",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationScaledObjectPatch: `{"spec": {"pollingInterval": 5, "triggers": [{"name": "default-trigger-custom", "metadata": {"threshold": "10"}}]}}`,
+		},
+	}, {
+		name: "scaled object patch with override",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationScaledObjectPatch:     `{"spec": {"pollingInterval": 5}}`,
+			KedaAutoscaleAnnotationsScaledObjectOverride: `{"spec": {"pollingInterval": 5}}`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationScaledObjectPatch},
+	}, {
+		name: "invalid json",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationScalingModifiers:        `{"formula": `,
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2"`,
+			KedaAutoscalingAnnotationHPAScaleDownRules:     `{"stabilizationWindowSeconds": "ten"}`,
+			KedaAutoscaleAnnotationsScaledObjectOverride:   `{`,
+		},
+		wantPaths: []string{
+			KedaAutoscaleAnnotationScalingModifiers,
+			KedaAutoscaleAnnotationExtraPrometheusTriggers,
+			KedaAutoscalingAnnotationHPAScaleDownRules,
+			KedaAutoscaleAnnotationsScaledObjectOverride,
+		},
+	}, {
+		name: "invalid metric type",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationMetricType: "Average",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationMetricType},
+	}, {
+		name: "invalid activate from zero",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationActivateFromZero: "yes",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationActivateFromZero},
+	}, {
+		name: "missing query",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey: "http_requests_total",
+			autoscaling.TargetAnnotationKey: "5",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusQuery},
+	}, {
+		name: "invalid query template",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:        "http_requests_total",
+			autoscaling.TargetAnnotationKey:        "5",
+			KedaAutoscaleAnnotationPrometheusQuery: `sum(rate(http_requests_total{pod=~"{{.revisionName"}[1m]))`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusQuery},
+	}, {
+		name: "invalid promql",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "http_requests_total",
+			autoscaling.TargetAnnotationKey:                "5",
+			KedaAutoscaleAnnotationPrometheusQuery:         `sum(rate(http_requests_total{pod=~"{{.revisionName}}.*"}[1m])`,
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"query": "sum by (pod) (up)"}}]`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusQuery, KedaAutoscaleAnnotationExtraPrometheusTriggers},
+	}, {
+		name: "invalid extra trigger template",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "trigger2", "type": "prometheus", "metadata": {"query": "sum(up{namespace=\"{{ .namespace \"})"}}]`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationExtraPrometheusTriggers},
+	}, {
+		name: "invalid prometheus address",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:          "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusAddress: "prometheus operated",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusAddress},
+	}, {
+		name: "extra trigger named like the default trigger",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:                "http_requests_total",
+			autoscaling.TargetAnnotationKey:                "5",
+			KedaAutoscaleAnnotationPrometheusQuery:         "sum(http_requests_total)",
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "default-trigger-custom", "type": "prometheus", "metadata": {"query": "sum(up)"}}]`,
+		},
+		wantPaths: []string{autoscaling.MetricAnnotationKey, KedaAutoscaleAnnotationExtraPrometheusTriggers},
+	}, {
+		name: "extra triggers with the same name",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "up", "type": "prometheus", "metadata": {"query": "sum(up)"}}, {"name": "up", "type": "prometheus", "metadata": {"query": "max(up)"}}]`,
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationExtraPrometheusTriggers},
+	}, {
+		name: "extra trigger named like the implicit cpu trigger",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationExtraPrometheusTriggers: `[{"name": "default-trigger-cpu", "type": "prometheus", "metadata": {"query": "sum(up)"}}]`,
+		},
+		wantPaths: []string{autoscaling.MetricAnnotationKey, KedaAutoscaleAnnotationExtraPrometheusTriggers},
+	}, {
+		name: "eventing rabbitmq auth",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationEventingTriggers:         "true",
+			KedaAutoscaleAnnotationEventingRabbitmqAuthName: "rabbitmq-auth",
+			KedaAutoscaleAnnotationEventingRabbitmqAuthKind: "ClusterTriggerAuthentication",
+		},
+	}, {
+		name: "eventing rabbitmq auth kind without name",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationEventingRabbitmqAuthKind: "TriggerAuthentication",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationEventingRabbitmqAuthName},
+	}, {
+		name: "invalid eventing rabbitmq auth kind",
+		annotations: map[string]string{
+			KedaAutoscaleAnnotationEventingRabbitmqAuthName: "rabbitmq-auth",
+			KedaAutoscaleAnnotationEventingRabbitmqAuthKind: "Secret",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationEventingRabbitmqAuthKind},
+	}, {
+		name: "auth modes missing",
+		annotations: map[string]string{
+			autoscaling.MetricAnnotationKey:           "http_requests_total",
+			KedaAutoscaleAnnotationPrometheusAuthName: "keda-trigger-auth-prometheus",
+		},
+		wantPaths: []string{KedaAutoscaleAnnotationPrometheusAuthModes},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAnnotations(tt.annotations)
+			if len(tt.wantPaths) == 0 {
+				if err != nil {
+					t.Fatal("ValidateAnnotations() =", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateAnnotations() = nil, want errors for %v", tt.wantPaths)
+			}
+			for _, path := range tt.wantPaths {
+				if !strings.Contains(err.Error(), path) {
+					t.Errorf("ValidateAnnotations() = %v, want an error for %s", err, path)
+				}
+			}
+		})
+	}
+}
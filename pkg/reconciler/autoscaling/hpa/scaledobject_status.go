@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+const (
+	// PodAutoscalerConditionScaledObjectReady mirrors the Ready condition of the ScaledObject.
+	PodAutoscalerConditionScaledObjectReady apis.ConditionType = "ScaledObjectReady"
+	// PodAutoscalerConditionScaledObjectActive mirrors the Active condition of the ScaledObject.
+	PodAutoscalerConditionScaledObjectActive apis.ConditionType = "ScaledObjectActive"
+	// PodAutoscalerConditionScaledObjectFallback mirrors the Fallback condition of the ScaledObject.
+	PodAutoscalerConditionScaledObjectFallback apis.ConditionType = "ScaledObjectFallback"
+	// PodAutoscalerConditionScaledObjectPaused mirrors the Paused condition of the ScaledObject.
+	PodAutoscalerConditionScaledObjectPaused apis.ConditionType = "ScaledObjectPaused"
+
+	// PodAutoscalerConditionTriggersHealthy reports whether KEDA is able to fetch the metrics
+	// of all the ScaledObject triggers.
+	PodAutoscalerConditionTriggersHealthy apis.ConditionType = "TriggersHealthy"
+
+	scaledObjectNotReadyReason  = "ScaledObjectNotReady"
+	scaledObjectPausedReason    = "ScaledObjectPaused"
+	scaledObjectFallbackReason  = "ScaledObjectFallback"
+	triggerFailingReason        = "TriggerFailing"
+	triggersRecoveredReason     = "TriggersRecovered"
+	triggersHealthUnknownReason = "TriggersHealthUnknown"
+)
+
+// metricNameIndex matches the trigger index KEDA prefixes the metric names with, e.g. "s0-prometheus".
+var metricNameIndex = regexp.MustCompile(`^s(\d+)-`)
+
+// scaledObjectConditions maps the ScaledObject conditions to the PA conditions mirroring them.
+var scaledObjectConditions = map[v1alpha1.ConditionType]apis.ConditionType{
+	v1alpha1.ConditionReady:    PodAutoscalerConditionScaledObjectReady,
+	v1alpha1.ConditionActive:   PodAutoscalerConditionScaledObjectActive,
+	v1alpha1.ConditionFallback: PodAutoscalerConditionScaledObjectFallback,
+	v1alpha1.ConditionPaused:   PodAutoscalerConditionScaledObjectPaused,
+}
+
+// propagateScaledObjectStatus mirrors the conditions of the ScaledObject onto the PA.
+// The mirrored conditions are informational and do not affect the readiness of the PA,
+// but a ScaledObject that is not ready means KEDA is not scaling the revision, so the
+// PA is not reported as active in that case.
+// The Revision only reflects the reason and message of the PA's Active condition while
+// it is not true, so an active PA whose ScaledObject is paused or scales to its fallback
+// replicas is reported as activating, which does not affect the readiness of the Revision.
+func propagateScaledObjectStatus(pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) {
+	if scaledObj == nil {
+		return
+	}
+	manager := pa.GetConditionSet().Manage(&pa.Status)
+	for _, cond := range scaledObj.Status.Conditions {
+		t, ok := scaledObjectConditions[cond.Type]
+		if !ok {
+			continue
+		}
+		switch cond.Status {
+		case metav1.ConditionTrue:
+			manager.MarkTrueWithReason(t, cond.Reason, "%s", cond.Message)
+		case metav1.ConditionFalse:
+			manager.MarkFalse(t, cond.Reason, "%s", cond.Message)
+		default:
+			manager.MarkUnknown(t, cond.Reason, "%s", cond.Message)
+		}
+	}
+
+	if ready := scaledObj.Status.Conditions.GetReadyCondition(); ready.IsFalse() {
+		reason := ready.Reason
+		if reason == "" {
+			reason = scaledObjectNotReadyReason
+		}
+		pa.Status.MarkActivating(reason, ready.Message)
+		return
+	}
+
+	if !pa.Status.IsActive() {
+		return
+	}
+	if paused := scaledObj.Status.Conditions.GetPausedCondition(); paused.IsTrue() {
+		message := paused.Message
+		if message == "" {
+			message = v1alpha1.ScaledObjectConditionPausedMessage
+		}
+		pa.Status.MarkActivating(scaledObjectPausedReason, message)
+	} else if fallback := scaledObj.Status.Conditions.GetFallbackCondition(); fallback.IsTrue() {
+		pa.Status.MarkActivating(scaledObjectFallbackReason, fallback.Message)
+	}
+}
+
+// propagateTriggerHealth reports the triggers of the ScaledObject that KEDA fails to fetch
+// metrics for. An event is emitted each time the set of failing triggers or their number of
+// consecutive failures changes, and when all triggers recover. Once KEDA reports no health,
+// e.g. after the failing triggers were removed, the condition turns unknown.
+func propagateTriggerHealth(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) {
+	if scaledObj == nil {
+		return
+	}
+	recorder := controller.GetEventRecorder(ctx)
+	manager := pa.GetConditionSet().Manage(&pa.Status)
+	prev := manager.GetCondition(PodAutoscalerConditionTriggersHealthy)
+
+	if len(scaledObj.Status.Health) == 0 {
+		if prev != nil && !prev.IsUnknown() {
+			manager.MarkUnknown(PodAutoscalerConditionTriggersHealthy, triggersHealthUnknownReason,
+				"KEDA reports no health for the triggers of ScaledObject %q", scaledObj.Name)
+		}
+		return
+	}
+
+	metricNames := make([]string, 0, len(scaledObj.Status.Health))
+	for metricName := range scaledObj.Status.Health {
+		metricNames = append(metricNames, metricName)
+	}
+	sort.Strings(metricNames)
+
+	var failures []string
+	for _, metricName := range metricNames {
+		health := scaledObj.Status.Health[metricName]
+		if health.Status != v1alpha1.HealthStatusFailing {
+			continue
+		}
+		var count int32
+		if health.NumberOfFailures != nil {
+			count = *health.NumberOfFailures
+		}
+		failures = append(failures, fmt.Sprintf("trigger %q failed %d consecutive times", triggerName(scaledObj, metricName), count))
+	}
+
+	if len(failures) == 0 {
+		if prev != nil && prev.IsFalse() && recorder != nil {
+			recorder.Eventf(pa, corev1.EventTypeNormal, triggersRecoveredReason,
+				"All triggers of ScaledObject %q are healthy", scaledObj.Name)
+		}
+		manager.MarkTrue(PodAutoscalerConditionTriggersHealthy)
+		return
+	}
+
+	message := strings.Join(failures, "; ")
+	if (prev == nil || !prev.IsFalse() || prev.Message != message) && recorder != nil {
+		recorder.Eventf(pa, corev1.EventTypeWarning, triggerFailingReason,
+			"ScaledObject %q: %s", scaledObj.Name, message)
+	}
+	manager.MarkFalse(PodAutoscalerConditionTriggersHealthy, triggerFailingReason, "%s", message)
+}
+
+// triggerName returns the name of the trigger KEDA reports the health of under the given
+// metric name, or the metric name itself if the trigger has no name.
+func triggerName(scaledObj *v1alpha1.ScaledObject, metricName string) string {
+	m := metricNameIndex.FindStringSubmatch(metricName)
+	if m == nil {
+		return metricName
+	}
+	i, err := strconv.Atoi(m[1])
+	if err != nil || i >= len(scaledObj.Spec.Triggers) || scaledObj.Spec.Triggers[i].Name == "" {
+		return metricName
+	}
+	return scaledObj.Spec.Triggers[i].Name
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"testing"
+
+	kedav1alpha1 "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+)
+
+func TestTriggerName(t *testing.T) {
+	scaledObj := &kedav1alpha1.ScaledObject{
+		Spec: kedav1alpha1.ScaledObjectSpec{
+			Triggers: []kedav1alpha1.ScaleTriggers{
+				{Name: "default-trigger-custom", Type: "prometheus"},
+				{Type: "prometheus"},
+			},
+		},
+	}
+
+	tests := []struct {
+		metricName string
+		want       string
+	}{{
+		metricName: "s0-prometheus",
+		want:       "default-trigger-custom",
+	}, {
+		metricName: "s1-prometheus",
+		want:       "s1-prometheus",
+	}, {
+		metricName: "s2-prometheus",
+		want:       "s2-prometheus",
+	}, {
+		metricName: "composite-metric",
+		want:       "composite-metric",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.metricName, func(t *testing.T) {
+			if got := triggerName(scaledObj, tt.metricName); got != tt.want {
+				t.Errorf("triggerName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropagateScaledObjectStatusToRevision(t *testing.T) {
+	ready := kedav1alpha1.Condition{Type: kedav1alpha1.ConditionReady, Status: metav1.ConditionTrue,
+		Reason: kedav1alpha1.ScaledObjectConditionReadySuccessReason}
+
+	tests := []struct {
+		name        string
+		conditions  kedav1alpha1.Conditions
+		wantStatus  corev1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{{
+		name:       "ready",
+		conditions: kedav1alpha1.Conditions{ready},
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name: "not ready",
+		conditions: kedav1alpha1.Conditions{{Type: kedav1alpha1.ConditionReady, Status: metav1.ConditionFalse,
+			Reason: "ScaledObjectCheckFailed", Message: "prometheus is unreachable"}},
+		wantStatus:  corev1.ConditionUnknown,
+		wantReason:  "ScaledObjectCheckFailed",
+		wantMessage: "prometheus is unreachable",
+	}, {
+		name: "paused",
+		conditions: kedav1alpha1.Conditions{ready, {Type: kedav1alpha1.ConditionPaused, Status: metav1.ConditionTrue,
+			Reason: kedav1alpha1.ScaledObjectConditionPausedReason}},
+		wantStatus:  corev1.ConditionUnknown,
+		wantReason:  scaledObjectPausedReason,
+		wantMessage: kedav1alpha1.ScaledObjectConditionPausedMessage,
+	}, {
+		name: "fallback",
+		conditions: kedav1alpha1.Conditions{ready, {Type: kedav1alpha1.ConditionFallback, Status: metav1.ConditionTrue,
+			Reason: "FallbackExists", Message: "At least one trigger is falling back on this scaled object"}},
+		wantStatus:  corev1.ConditionUnknown,
+		wantReason:  scaledObjectFallbackReason,
+		wantMessage: "At least one trigger is falling back on this scaled object",
+	}, {
+		name: "no fallback",
+		conditions: kedav1alpha1.Conditions{ready, {Type: kedav1alpha1.ConditionFallback, Status: metav1.ConditionFalse,
+			Reason: "NoFallbackFound"}},
+		wantStatus: corev1.ConditionTrue,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision)
+			pa.Status.InitializeConditions()
+			pa.Status.MarkSKSReady()
+			pa.Status.MarkScaleTargetInitialized()
+			pa.Status.MarkActive()
+			pa.Status.ServiceName = helpers.TestRevision
+
+			scaledObj := &kedav1alpha1.ScaledObject{Status: kedav1alpha1.ScaledObjectStatus{Conditions: tt.conditions}}
+			propagateScaledObjectStatus(pa, scaledObj)
+
+			rs := &servingv1.RevisionStatus{}
+			rs.InitializeConditions()
+			rs.PropagateAutoscalerStatus(&pa.Status)
+
+			cond := rs.GetCondition(servingv1.RevisionConditionActive)
+			if cond == nil {
+				t.Fatal("Revision has no Active condition")
+			}
+			if cond.Status != tt.wantStatus || cond.Reason != tt.wantReason || cond.Message != tt.wantMessage {
+				t.Errorf("Revision Active = %s/%q/%q, want: %s/%q/%q", cond.Status, cond.Reason, cond.Message,
+					tt.wantStatus, tt.wantReason, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestPropagateScaledObjectConditionMessage(t *testing.T) {
+	const message = "query returned 100% of the samples"
+	pa := helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision)
+	pa.Status.InitializeConditions()
+
+	scaledObj := &kedav1alpha1.ScaledObject{Status: kedav1alpha1.ScaledObjectStatus{Conditions: kedav1alpha1.Conditions{{
+		Type: kedav1alpha1.ConditionActive, Status: metav1.ConditionFalse, Reason: "ScalerNotActive", Message: message,
+	}}}}
+	propagateScaledObjectStatus(pa, scaledObj)
+
+	if cond := pa.Status.GetCondition(PodAutoscalerConditionScaledObjectActive); cond == nil || cond.Message != message {
+		t.Errorf("ScaledObjectActive = %v, want message: %q", cond, message)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+
+	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
+)
+
+const (
+	// PodAutoscalerConditionScaledObjectValid reports whether the ScaledObject brought by
+	// the user matches the revision it scales.
+	PodAutoscalerConditionScaledObjectValid apis.ConditionType = "ScaledObjectValid"
+
+	scaledObjectInvalidReason = "ScaledObjectInvalid"
+
+	// defaultMaxReplicaCount is the max replicas KEDA uses when none is specified.
+	defaultMaxReplicaCount = 100
+)
+
+// validateScaledObject checks that a ScaledObject brought by the user targets the PA's
+// scale target, respects the revision's scale bounds and has triggers. Mismatches are
+// reported in the ScaledObjectValid condition of the PA and as warning events.
+// It returns false if the ScaledObject does not scale the PA's scale target at all.
+func validateScaledObject(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) bool {
+	problems := scaledObjectMismatches(ctx, pa, scaledObj)
+	manager := pa.GetConditionSet().Manage(&pa.Status)
+	if len(problems) == 0 {
+		manager.MarkTrue(PodAutoscalerConditionScaledObjectValid)
+		return true
+	}
+
+	message := fmt.Sprintf("ScaledObject %q: %s", scaledObj.Name, strings.Join(problems, "; "))
+	prev := manager.GetCondition(PodAutoscalerConditionScaledObjectValid)
+	if prev == nil || !prev.IsFalse() || prev.Message != message {
+		controller.GetEventRecorder(ctx).Event(pa, corev1.EventTypeWarning, scaledObjectInvalidReason, message)
+	}
+	manager.MarkFalse(PodAutoscalerConditionScaledObjectValid, scaledObjectInvalidReason, message)
+	return targetsDeployment(scaledObj, pa.Spec.ScaleTargetRef.Name)
+}
+
+// scaledObjectMismatches returns the reasons why the ScaledObject does not fit the PA.
+func scaledObjectMismatches(ctx context.Context, pa *autoscalingv1alpha1.PodAutoscaler, scaledObj *v1alpha1.ScaledObject) []string {
+	var problems []string
+	if target := pa.Spec.ScaleTargetRef.Name; !targetsDeployment(scaledObj, target) {
+		problems = append(problems, fmt.Sprintf("scaleTargetRef does not point at deployment %q", target))
+	}
+
+	minScale, maxScale := pa.ScaleBounds(hpaconfig.FromContext(ctx).Autoscaler)
+	minReplicas, maxReplicas := int32(0), int32(defaultMaxReplicaCount)
+	if scaledObj.Spec.MinReplicaCount != nil {
+		minReplicas = *scaledObj.Spec.MinReplicaCount
+	}
+	if scaledObj.Spec.MaxReplicaCount != nil {
+		maxReplicas = *scaledObj.Spec.MaxReplicaCount
+	}
+	if minReplicas < minScale {
+		problems = append(problems, fmt.Sprintf("minReplicaCount %d is lower than min-scale %d", minReplicas, minScale))
+	}
+	if maxScale > 0 && maxReplicas > maxScale {
+		problems = append(problems, fmt.Sprintf("maxReplicaCount %d is higher than max-scale %d", maxReplicas, maxScale))
+	}
+	if minReplicas > maxReplicas {
+		problems = append(problems, fmt.Sprintf("minReplicaCount %d is higher than maxReplicaCount %d", minReplicas, maxReplicas))
+	}
+
+	if len(scaledObj.Spec.Triggers) == 0 {
+		problems = append(problems, "no triggers are defined")
+	}
+	return problems
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kedacore/keda/v2/apis/keda/v1alpha1"
+
+	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	. "knative.dev/serving/pkg/testing"
+
+	hpaconfig "knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/config"
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/helpers"
+)
+
+func TestScaledObjectMismatches(t *testing.T) {
+	ctx := hpaconfig.ToContext(context.Background(), defaultConfig())
+
+	tests := []struct {
+		name      string
+		pa        *autoscalingv1alpha1.PodAutoscaler
+		scaledObj func(*v1alpha1.ScaledObject)
+		want      []string
+	}{{
+		name: "valid",
+		pa:   helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass),
+	}, {
+		name: "wrong scale target",
+		pa:   helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass),
+		scaledObj: func(so *v1alpha1.ScaledObject) {
+			so.Spec.ScaleTargetRef.Name = "other-deployment"
+		},
+		want: []string{`scaleTargetRef does not point at deployment "test-revision-deployment"`},
+	}, {
+		name: "min replicas lower than min scale",
+		pa:   helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, withMinScale(2)),
+		scaledObj: func(so *v1alpha1.ScaledObject) {
+			so.Spec.MinReplicaCount = ptr.Int32(1)
+		},
+		want: []string{"minReplicaCount 1 is lower than min-scale 2"},
+	}, {
+		name: "max replicas higher than max scale",
+		pa: helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass,
+			helpers.WithAnnotations(map[string]string{autoscaling.MaxScaleAnnotationKey: "5"})),
+		scaledObj: func(so *v1alpha1.ScaledObject) {
+			so.Spec.MaxReplicaCount = nil
+		},
+		want: []string{"maxReplicaCount 100 is higher than max-scale 5"},
+	}, {
+		name: "min replicas higher than max replicas",
+		pa:   helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass),
+		scaledObj: func(so *v1alpha1.ScaledObject) {
+			so.Spec.MinReplicaCount = ptr.Int32(4)
+			so.Spec.MaxReplicaCount = ptr.Int32(3)
+		},
+		want: []string{"minReplicaCount 4 is higher than maxReplicaCount 3"},
+	}, {
+		name: "no triggers",
+		pa:   helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass),
+		scaledObj: func(so *v1alpha1.ScaledObject) {
+			so.Spec.Triggers = nil
+		},
+		want: []string{"no triggers are defined"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			so := scaledObject(helpers.PodAutoscaler(helpers.TestNamespace, helpers.TestRevision, WithHPAClass, WithMetricAnnotation("cpu")))
+			if tt.scaledObj != nil {
+				tt.scaledObj(so)
+			}
+			if got := scaledObjectMismatches(ctx, tt.pa, so); !cmp.Equal(got, tt.want) {
+				t.Errorf("scaledObjectMismatches() = %v, want: %v", got, tt.want)
+			}
+		})
+	}
+}
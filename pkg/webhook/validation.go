@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/webhook/resourcesemantics"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/resources"
+)
+
+// Types are the resources whose KEDA autoscaling annotations are validated
+// by the admission webhook.
+var Types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+	servingv1.SchemeGroupVersion.WithKind("Service"):       &Service{},
+	servingv1.SchemeGroupVersion.WithKind("Configuration"): &Configuration{},
+	servingv1.SchemeGroupVersion.WithKind("Revision"):      &Revision{},
+}
+
+// Service validates the KEDA autoscaling annotations of the revision template
+// of a Knative Service. The rest of the Service is validated by Knative Serving.
+type Service struct {
+	servingv1.Service
+}
+
+// Configuration validates the KEDA autoscaling annotations of the revision
+// template of a Knative Configuration.
+type Configuration struct {
+	servingv1.Configuration
+}
+
+// Revision validates the KEDA autoscaling annotations of a Knative Revision.
+type Revision struct {
+	servingv1.Revision
+}
+
+var (
+	_ resourcesemantics.GenericCRD  = (*Service)(nil)
+	_ resourcesemantics.VerbLimited = (*Service)(nil)
+	_ resourcesemantics.GenericCRD  = (*Configuration)(nil)
+	_ resourcesemantics.VerbLimited = (*Configuration)(nil)
+	_ resourcesemantics.GenericCRD  = (*Revision)(nil)
+	_ resourcesemantics.VerbLimited = (*Revision)(nil)
+)
+
+// supportedVerbs are the operations the annotations are validated on.
+var supportedVerbs = []admissionregistrationv1.OperationType{
+	admissionregistrationv1.Create,
+	admissionregistrationv1.Update,
+}
+
+// Validate implements apis.Validatable.
+func (s *Service) Validate(context.Context) *apis.FieldError {
+	return validateAnnotations(s.Spec.Template.ObjectMeta).ViaField("spec", "template")
+}
+
+// DeepCopyObject implements runtime.Object.
+func (s *Service) DeepCopyObject() runtime.Object {
+	return &Service{Service: *s.Service.DeepCopy()}
+}
+
+// SupportedVerbs implements resourcesemantics.VerbLimited.
+func (s *Service) SupportedVerbs() []admissionregistrationv1.OperationType {
+	return supportedVerbs
+}
+
+// Validate implements apis.Validatable.
+func (c *Configuration) Validate(context.Context) *apis.FieldError {
+	return validateAnnotations(c.Spec.Template.ObjectMeta).ViaField("spec", "template")
+}
+
+// DeepCopyObject implements runtime.Object.
+func (c *Configuration) DeepCopyObject() runtime.Object {
+	return &Configuration{Configuration: *c.Configuration.DeepCopy()}
+}
+
+// SupportedVerbs implements resourcesemantics.VerbLimited.
+func (c *Configuration) SupportedVerbs() []admissionregistrationv1.OperationType {
+	return supportedVerbs
+}
+
+// Validate implements apis.Validatable.
+func (r *Revision) Validate(context.Context) *apis.FieldError {
+	return validateAnnotations(r.ObjectMeta)
+}
+
+// DeepCopyObject implements runtime.Object.
+func (r *Revision) DeepCopyObject() runtime.Object {
+	return &Revision{Revision: *r.Revision.DeepCopy()}
+}
+
+// SupportedVerbs implements resourcesemantics.VerbLimited.
+func (r *Revision) SupportedVerbs() []admissionregistrationv1.OperationType {
+	return supportedVerbs
+}
+
+// validateAnnotations validates the KEDA autoscaling annotations of revisions
+// using the HPA autoscaler class.
+func validateAnnotations(meta metav1.ObjectMeta) *apis.FieldError {
+	if meta.Annotations[autoscaling.ClassAnnotationKey] != autoscaling.HPA {
+		return nil
+	}
+	return resources.ValidateAnnotations(meta.Annotations).ViaField("metadata", "annotations")
+}
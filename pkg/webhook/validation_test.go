@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/apis"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	"knative.dev/autoscaler-keda/pkg/reconciler/autoscaling/hpa/resources"
+)
+
+func TestValidate(t *testing.T) {
+	invalid := map[string]string{
+		autoscaling.ClassAnnotationKey:                    autoscaling.HPA,
+		resources.KedaAutoscaleAnnotationScalingModifiers: `{"formula": `,
+	}
+	otherClass := map[string]string{
+		autoscaling.ClassAnnotationKey:                    autoscaling.KPA,
+		resources.KedaAutoscaleAnnotationScalingModifiers: `{"formula": `,
+	}
+	template := func(annotations map[string]string) servingv1.RevisionTemplateSpec {
+		return servingv1.RevisionTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	tests := []struct {
+		name     string
+		obj      apis.Validatable
+		wantPath string
+	}{{
+		name: "service",
+		obj: &Service{servingv1.Service{Spec: servingv1.ServiceSpec{
+			ConfigurationSpec: servingv1.ConfigurationSpec{Template: template(invalid)},
+		}}},
+		wantPath: "spec.template.metadata.annotations." + resources.KedaAutoscaleAnnotationScalingModifiers,
+	}, {
+		name: "configuration",
+		obj: &Configuration{servingv1.Configuration{Spec: servingv1.ConfigurationSpec{
+			Template: template(invalid),
+		}}},
+		wantPath: "spec.template.metadata.annotations." + resources.KedaAutoscaleAnnotationScalingModifiers,
+	}, {
+		name:     "revision",
+		obj:      &Revision{servingv1.Revision{ObjectMeta: metav1.ObjectMeta{Annotations: invalid}}},
+		wantPath: "metadata.annotations." + resources.KedaAutoscaleAnnotationScalingModifiers,
+	}, {
+		name: "other autoscaler class",
+		obj: &Service{servingv1.Service{Spec: servingv1.ServiceSpec{
+			ConfigurationSpec: servingv1.ConfigurationSpec{Template: template(otherClass)},
+		}}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.obj.Validate(context.Background())
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Fatal("Validate() =", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() = nil, wanted an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantPath) {
+				t.Errorf("Validate() = %v, want an error for %s", err, tt.wantPath)
+			}
+		})
+	}
+}